@@ -2,11 +2,13 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"io/ioutil"
-	"unicode"
+	"os"
 
 	"github.com/golang/freetype/truetype"
+	"github.com/macroblock/exp/pkg/ui/fontface"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/goregular"
 	"golang.org/x/image/math/fixed"
@@ -17,33 +19,37 @@ type (
 	Range struct{ min, max rune }
 )
 
-func ttfStats(ttf *truetype.Font) []*Range {
+func ttfStats(data []byte) ([]*Range, error) {
+	ranges, err := fontface.Coverage(data, 0)
+	if err != nil {
+		return nil, err
+	}
 	slice := []*Range{}
-	hasReplacementRune := ttf.Index(0xfffd) != 0
-
-	rng := (*Range)(nil)
-	for r := rune(0); r <= unicode.MaxRune; r++ {
-		if 0xe000 <= r && r <= 0xf8ff ||
-			0xf0000 <= r && r <= 0xffffd ||
-			0x100000 <= r && r <= 0x10fffd {
-			rng = nil
-			continue
-		}
-		if ttf.Index(r) == 0 {
-			rng = nil
-			continue
+	hasReplacementRune := false
+	for _, v := range ranges {
+		if v.Begin <= 0xfffd && 0xfffd < v.End {
+			hasReplacementRune = true
 		}
-		if rng == nil {
-			rng = &Range{min: r}
-			slice = append(slice, rng)
+		// private use areas are left out, faces do not load their glyphs
+		rng := (*Range)(nil)
+		for r := v.Begin; r < v.End; r++ {
+			if fontface.IsPrivateUse(r) {
+				rng = nil
+				continue
+			}
+			if rng == nil {
+				rng = &Range{min: r}
+				slice = append(slice, rng)
+			}
+			rng.max = r + 1
 		}
-		rng.max = r + 1
 	}
 	fmt.Printf("replacement rune: %v\n", hasReplacementRune)
-	return slice
+	return slice, nil
 }
 
-func faceStats(face font.Face) []*Range {
+// faceStats checks that the face can render the code points of the ranges.
+func faceStats(face font.Face, ranges []*Range) []*Range {
 	slice := []*Range{}
 	hasReplacementRune := false
 	if _, ok := face.GlyphAdvance(0xfffd); ok {
@@ -51,30 +57,30 @@ func faceStats(face font.Face) []*Range {
 	}
 
 	rng := (*Range)(nil)
-	for r := rune(0); r <= unicode.MaxRune; r++ {
-		if 0xe000 <= r && r <= 0xf8ff ||
-			0xf0000 <= r && r <= 0xffffd ||
-			0x100000 <= r && r <= 0x10fffd {
-			rng = nil
-			continue
-		}
-		// if _, ok := face.GlyphAdvance(0xfffd); !ok {
-		// if _, _, ok := face.GlyphBounds(r); !ok {
-		if _, _, _, _, ok := face.Glyph(fixed.Point26_6{}, r); !ok {
-			rng = nil
-			continue
+	for _, v := range ranges {
+		rng = nil
+		for r := v.min; r < v.max; r++ {
+			// if _, ok := face.GlyphAdvance(0xfffd); !ok {
+			// if _, _, ok := face.GlyphBounds(r); !ok {
+			if _, _, _, _, ok := face.Glyph(fixed.Point26_6{}, r); !ok {
+				rng = nil
+				continue
+			}
+			if rng == nil {
+				rng = &Range{min: r}
+				slice = append(slice, rng)
+			}
+			rng.max = r + 1
 		}
-		if rng == nil {
-			rng = &Range{min: r}
-			slice = append(slice, rng)
-		}
-		rng.max = r + 1
 	}
 	fmt.Printf("replacement rune: %v\n", hasReplacementRune)
 	return slice
 }
 
 func main() {
+	subset := flag.String("subset", "", "write a subset of the font holding the runes of the text")
+	out := flag.String("o", "subset.ttf", "output file of -subset")
+	flag.Parse()
 
 	data, err := ioutil.ReadAll(bytes.NewReader(goregular.TTF))
 	if err != nil {
@@ -82,14 +88,31 @@ func main() {
 		return
 	}
 
+	if *subset != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Println("create: ", err)
+			return
+		}
+		defer f.Close()
+		err = fontface.Subset(f, data, []rune(*subset))
+		if err != nil {
+			fmt.Println("subset: ", err)
+			return
+		}
+	}
+
 	ttf, err := truetype.Parse(data)
 	if err != nil {
 		fmt.Println("truetype parse: ", err)
 		return
 	}
 
-	ttfSlice := ttfStats(ttf)
-	_ = ttfSlice
+	ttfSlice, err := ttfStats(data)
+	if err != nil {
+		fmt.Println("coverage: ", err)
+		return
+	}
 
 	face := truetype.NewFace(ttf, &truetype.Options{
 		Size:       float64(14),
@@ -99,7 +122,7 @@ func main() {
 	})
 	defer face.Close()
 
-	faceSlice := faceStats(face)
+	faceSlice := faceStats(face, ttfSlice)
 	_ = faceSlice
 	ttfSlice = faceSlice
 
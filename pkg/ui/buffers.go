@@ -17,187 +17,228 @@ type IBuffer interface {
 	Size() int
 }
 
-// TBuffer -
-type TBuffer struct {
-	id        uint32
-	typ       uint32
-	len       int
-	size      int
-	usageHint uint32
+// IElementBuffer - an element array buffer of any index type.
+type IElementBuffer interface {
+	Bind()
+	Unbind()
+	Draw(mode uint32)
 }
 
-// TArrayBuffer -
-type TArrayBuffer struct {
+// TIndex - the index types of element array buffers.
+type TIndex interface {
+	~uint8 | ~uint16 | ~uint32
+}
+
+// TBuffer -
+type TBuffer struct {
+	dev        *tDevice
+	id         uint32
+	typ        uint32
+	components int
+	stride     int
+	len        int
+	size       int
+	usageHint  uint32
+}
+
+// TArrayBuffer - a buffer of vertices of type T: a scalar, an array or a
+// struct of them, e.g. float32, [4]float32, mgl32.Vec2 or
+// struct{ Pos mgl32.Vec2; UV mgl32.Vec2 }.
+type TArrayBuffer[T any] struct {
 	TBuffer
 }
 
-// TElementArrayBuffer -
-type TElementArrayBuffer struct {
+// TElementArrayBuffer - a buffer of indices of type T.
+type TElementArrayBuffer[T TIndex] struct {
 	TBuffer
 }
 
 // NewBuffer -
 func NewBuffer() *TBuffer {
-	ret := &TBuffer{usageHint: gl.STATIC_DRAW}
-	gl.GenBuffers(1, &ret.id)
-	return ret
+	return newBuffer(dev)
+}
+
+func newBuffer(d *tDevice) *TBuffer {
+	return &TBuffer{dev: d, id: d.GenBuffer(), usageHint: gl.STATIC_DRAW}
 }
 
 // Bind -
 func (o *TBuffer) Bind(target uint32) {
-	gl.BindBuffer(target, o.id)
+	o.dev.BindBuffer(target, o.id)
 }
 
 // Unbind -
 func (o *TBuffer) Unbind(target uint32) {
-	gl.BindBuffer(target, 0)
+	o.dev.BindBuffer(target, 0)
 }
 
-// Type -
+// Type - the GL type of the scalars of an element, 0 if they differ.
 func (o *TBuffer) Type() uint32 { return o.typ }
 
-// Len -
+// Components - the number of scalars in an element.
+func (o *TBuffer) Components() int { return o.components }
+
+// Stride - the size of an element in bytes.
+func (o *TBuffer) Stride() int { return o.stride }
+
+// Len - the number of elements.
 func (o *TBuffer) Len() int { return o.len }
 
-// Size -
+// Size - the size of the data in bytes.
 func (o *TBuffer) Size() int { return o.size }
 
-// Data -
-func (o *TBuffer) Data(target uint32, data interface{}, usageHint ...uint32) {
-	o.typ, o.size = loadData2(o, target, data, usageHint...)
+// Delete -
+func (o *TBuffer) Delete() {
+	if o.id != 0 {
+		o.dev.DeleteBuffer(o.id)
+		o.id = 0
+	}
 }
 
 // NewArrayBuffer -
-func NewArrayBuffer() *TArrayBuffer {
-	ret := &TArrayBuffer{TBuffer: *NewBuffer()}
-	gl.GenBuffers(1, &ret.id)
-	return ret
+func NewArrayBuffer[T any]() *TArrayBuffer[T] {
+	return newArrayBuffer[T](dev)
+}
+
+func newArrayBuffer[T any](d *tDevice) *TArrayBuffer[T] {
+	return &TArrayBuffer[T]{TBuffer: *newBuffer(d)}
 }
 
 // Bind -
-func (o *TArrayBuffer) Bind() {
-	gl.BindBuffer(gl.ARRAY_BUFFER, o.id)
+func (o *TArrayBuffer[T]) Bind() {
+	o.dev.BindBuffer(gl.ARRAY_BUFFER, o.id)
 }
 
 // Unbind -
-func (o *TArrayBuffer) Unbind() {
-	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+func (o *TArrayBuffer[T]) Unbind() {
+	o.dev.BindBuffer(gl.ARRAY_BUFFER, 0)
+}
+
+// Data - replaces the contents and the size of the bound buffer.
+func (o *TArrayBuffer[T]) Data(data []T, usageHint ...uint32) error {
+	return bufferData(&o.TBuffer, gl.ARRAY_BUFFER, data, usageHint...)
 }
 
-// Data -
-func (o *TArrayBuffer) Data(data interface{}, usageHint ...uint32) {
-	o.typ, o.size = loadData2(&o.TBuffer, gl.ARRAY_BUFFER, data, usageHint...)
+// SubData - replaces the elements from offset on with data, the buffer must
+// be bound and large enough.
+func (o *TArrayBuffer[T]) SubData(offset int, data []T) error {
+	return bufferSubData(&o.TBuffer, gl.ARRAY_BUFFER, offset, data)
 }
 
 // NewElementArrayBuffer -
-func NewElementArrayBuffer() *TElementArrayBuffer {
-	ret := &TElementArrayBuffer{TBuffer: *NewBuffer()}
-	gl.GenBuffers(1, &ret.id)
-	return ret
+func NewElementArrayBuffer[T TIndex]() *TElementArrayBuffer[T] {
+	return newElementArrayBuffer[T](dev)
+}
+
+func newElementArrayBuffer[T TIndex](d *tDevice) *TElementArrayBuffer[T] {
+	return &TElementArrayBuffer[T]{TBuffer: *newBuffer(d)}
 }
 
 // Bind -
-func (o *TElementArrayBuffer) Bind() {
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, o.id)
+func (o *TElementArrayBuffer[T]) Bind() {
+	o.dev.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, o.id)
 }
 
 // Unbind -
-func (o *TElementArrayBuffer) Unbind() {
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, 0)
-}
-
-// Data -
-func (o *TElementArrayBuffer) Data(data interface{}, usageHint ...uint32) {
-	o.typ, o.size = loadData2(&o.TBuffer, gl.ELEMENT_ARRAY_BUFFER, data, usageHint...)
-}
-
-// Draw -
-func (o TElementArrayBuffer) Draw(mode uint32) {
-	gl.DrawElements(mode, int32(o.size), o.typ, gl.PtrOffset(0)) //gl.Ptr(&ctx.elements[0]))
-}
-
-// func loadData(o *TBuffer, target uint32, data interface{}, usageHint ...uint32) int {
-// 	if len(usageHint) > 0 {
-// 		o.usageHint = usageHint[0]
-// 	}
-
-// 	val := reflect.ValueOf(data)
-// 	if val.Len() == 0 {
-// 		// ???
-// 		return 0
-// 	}
-// 	typ := reflect.TypeOf(data)
-// 	typeSize := typ.Elem().Size()
-// 	sliceLen := val.Len() // for slice, arrays or chan only
-// 	ptr := unsafe.Pointer(val.Pointer())
-// 	size := int(sliceLen) * int(typeSize)
-// 	// fmt.Println(sliceLen, " ", typeSize, " ", ptr)
-// 	gl.BufferData(
-// 		target,
-// 		size,
-// 		ptr,
-// 		o.usageHint)
-// 	// fmt.Printf("-> %v\n", *(*uint32)(ptr))
-// 	return size
-// }
-
-func loadData2(o *TBuffer, target uint32, data interface{}, usageHint ...uint32) (uint32, int) {
+func (o *TElementArrayBuffer[T]) Unbind() {
+	o.dev.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, 0)
+}
+
+// Data - replaces the contents and the size of the bound buffer.
+func (o *TElementArrayBuffer[T]) Data(data []T, usageHint ...uint32) error {
+	return bufferData(&o.TBuffer, gl.ELEMENT_ARRAY_BUFFER, data, usageHint...)
+}
+
+// SubData - replaces the indices from offset on with data, the buffer must
+// be bound and large enough.
+func (o *TElementArrayBuffer[T]) SubData(offset int, data []T) error {
+	return bufferSubData(&o.TBuffer, gl.ELEMENT_ARRAY_BUFFER, offset, data)
+}
+
+// Draw - draws all the indices, the buffer must be bound.
+func (o *TElementArrayBuffer[T]) Draw(mode uint32) {
+	o.dev.DrawElements(mode, int32(o.len), o.typ, 0)
+}
+
+// describeElem returns the GL type and the number of the scalars of T. The
+// type is 0 if the scalars differ.
+func describeElem[T any]() (uint32, int, error) {
+	var zero T
+	typ, n, err := describeType(reflect.TypeOf(&zero).Elem())
+	if err != nil {
+		return 0, 0, fmt.Errorf("buffer of %T: %v", zero, err)
+	}
+	return typ, n, nil
+}
+
+func describeType(t reflect.Type) (uint32, int, error) {
+	switch t.Kind() {
+	case reflect.Int8:
+		return gl.BYTE, 1, nil
+	case reflect.Uint8:
+		return gl.UNSIGNED_BYTE, 1, nil
+	case reflect.Int16:
+		return gl.SHORT, 1, nil
+	case reflect.Uint16:
+		return gl.UNSIGNED_SHORT, 1, nil
+	case reflect.Int32:
+		return gl.INT, 1, nil
+	case reflect.Uint32:
+		return gl.UNSIGNED_INT, 1, nil
+	case reflect.Float32:
+		return gl.FLOAT, 1, nil
+	case reflect.Float64:
+		return 0, 0, fmt.Errorf("%v is not supported by GLES, use float32", t)
+	case reflect.Array:
+		typ, n, err := describeType(t.Elem())
+		return typ, n * t.Len(), err
+	case reflect.Struct:
+		typ, n := uint32(0), 0
+		for i := 0; i < t.NumField(); i++ {
+			ftyp, fn, err := describeType(t.Field(i).Type)
+			if err != nil {
+				return 0, 0, fmt.Errorf("field %v: %v", t.Field(i).Name, err)
+			}
+			if i > 0 && ftyp != typ {
+				ftyp = 0
+			}
+			typ, n = ftyp, n+fn
+		}
+		if n == 0 {
+			return 0, 0, fmt.Errorf("%v has no fields", t)
+		}
+		return typ, n, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported type %v; must be a fixed size scalar, an array or a struct of them", t)
+}
+
+func bufferData[T any](o *TBuffer, target uint32, data []T, usageHint ...uint32) error {
+	typ, n, err := describeElem[T]()
+	if err != nil {
+		return err
+	}
 	if len(usageHint) > 0 {
 		o.usageHint = usageHint[0]
 	}
-	var elemTyp reflect.Type
-	var glType uint32
-
-	val := reflect.ValueOf(data)
-	typ := val.Type()
-	switch typ.Kind() {
-	default:
-		panic(fmt.Errorf("unsupported type %s; must be a slice or pointer to a scalar value", typ))
-	case reflect.Ptr:
-		panic(fmt.Errorf("not yet supported type %s", val.Type()))
-	case reflect.Slice:
-		elemTyp = typ.Elem()
-		o.len = val.Len() // for slice, arrays or chan only
-		switch elemTyp.Kind() {
-		default:
-			panic(fmt.Errorf("unsupported type of element %s; must be a scalar type", elemTyp))
-		case reflect.Int8:
-			glType = gl.BYTE
-		case reflect.Uint8:
-			glType = gl.UNSIGNED_BYTE
-		case reflect.Int16:
-			glType = gl.SHORT
-		case reflect.Uint16:
-			glType = gl.UNSIGNED_SHORT
-		case reflect.Int32:
-			glType = gl.INT
-			// switch elemVal.Interface().(type) {
-			// case fixed.Int16_16:
-			// elemType = gl.FIXED
-			// }
-		case reflect.Uint32:
-			glType = gl.UNSIGNED_INT
-		// case reflect.Float16:
-		// 	elemType = gl.HALF_FLOAT
-		case reflect.Float32:
-			glType = gl.FLOAT
-			// case reflect.Float64:
-			// 	elemType = gl.DOUBLE
-		}
+	o.typ, o.components = typ, n
+	o.stride = int(unsafe.Sizeof(*new(T)))
+	o.len = len(data)
+	o.size = o.len * o.stride
+	ptr := unsafe.Pointer(nil)
+	if len(data) > 0 {
+		ptr = unsafe.Pointer(&data[0])
+	}
+	o.dev.BufferData(target, o.size, ptr, o.usageHint)
+	return nil
+}
+
+func bufferSubData[T any](o *TBuffer, target uint32, offset int, data []T) error {
+	if offset < 0 || offset+len(data) > o.len {
+		return fmt.Errorf("buffer: %v elements at %v are out of %v", len(data), offset, o.len)
+	}
+	if len(data) == 0 {
+		return nil
 	}
-	ptr := unsafe.Pointer(val.Pointer())
-	typeSize := elemTyp.Size()
-	size := int(o.len) * int(typeSize)
-	// if val.Len() == 0 {
-	// 	// ???
-	// 	return glType, 0
-	// }
-	// fmt.Println(sliceLen, " ", typeSize, " ", ptr)
-	gl.BufferData(
-		target,
-		size,
-		ptr,
-		o.usageHint)
-	// fmt.Printf("-> %v\n", *(*uint32)(ptr))
-	return glType, size
+	o.dev.BufferSubData(target, offset*o.stride, len(data)*o.stride, unsafe.Pointer(&data[0]))
+	return nil
 }
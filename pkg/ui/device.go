@@ -0,0 +1,180 @@
+package ui
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// IDevice - the rendering commands the types of the package issue. The
+// methods follow OpenGL ES 3.0 and take its enums (gl.ARRAY_BUFFER,
+// gl.TRIANGLES, ...), but return names and queried values instead of
+// writing them through pointers. Offsets into bound buffers are passed as
+// plain ints.
+type IDevice interface {
+	Init() error
+	GetError() uint32
+	GetString(name uint32) string
+	GetIntegerv(pname uint32, data []int32)
+	Finish()
+
+	// state
+	Enable(capability uint32)
+	Disable(capability uint32)
+	BlendFunc(sfactor, dfactor uint32)
+	ColorMask(r, g, b, a bool)
+	StencilFunc(fn uint32, ref int32, mask uint32)
+	StencilMask(mask uint32)
+	StencilOp(fail, zfail, zpass uint32)
+	StencilOpSeparate(face, fail, zfail, zpass uint32)
+	Viewport(x, y, w, h int32)
+	ClearColor(r, g, b, a float32)
+	Clear(mask uint32)
+
+	// buffers
+	GenBuffer() uint32
+	DeleteBuffer(id uint32)
+	BindBuffer(target, id uint32)
+	BufferData(target uint32, size int, data unsafe.Pointer, usage uint32)
+	BufferSubData(target uint32, offset, size int, data unsafe.Pointer)
+	MapBufferRange(target uint32, offset, size int, access uint32) unsafe.Pointer
+	UnmapBuffer(target uint32) bool
+
+	// vertex arrays and draws
+	GenVertexArray() uint32
+	DeleteVertexArray(id uint32)
+	BindVertexArray(id uint32)
+	EnableVertexAttribArray(index uint32)
+	VertexAttribPointer(index uint32, size int32, xtype uint32, normalized bool, stride int32, offset int)
+	VertexAttribIPointer(index uint32, size int32, xtype uint32, stride int32, offset int)
+	DrawArrays(mode uint32, first, count int32)
+	DrawElements(mode uint32, count int32, xtype uint32, offset int)
+
+	// textures
+	GenTexture() uint32
+	DeleteTexture(id uint32)
+	ActiveTexture(unit uint32)
+	BindTexture(target, id uint32)
+	TexImage2D(target uint32, level, internalFormat, w, h int32, format, xtype uint32, pix unsafe.Pointer)
+	TexSubImage2D(target uint32, level, x, y, w, h int32, format, xtype uint32, pix unsafe.Pointer)
+	TexParameteri(target, pname uint32, param int32)
+	GenerateMipmap(target uint32)
+	PixelStorei(pname uint32, param int32)
+
+	// framebuffers
+	GenFramebuffer() uint32
+	DeleteFramebuffer(id uint32)
+	BindFramebuffer(target, id uint32)
+	CheckFramebufferStatus(target uint32) uint32
+	FramebufferTexture2D(target, attachment, texTarget, texture uint32, level int32)
+	FramebufferRenderbuffer(target, attachment, rbTarget, renderbuffer uint32)
+	GetFramebufferAttachmentParameteri(target, attachment, pname uint32) int32
+	GenRenderbuffer() uint32
+	DeleteRenderbuffer(id uint32)
+	BindRenderbuffer(target, id uint32)
+	RenderbufferStorage(target, internalFormat uint32, w, h int32)
+	ReadBuffer(src uint32)
+	ReadPixels(x, y, w, h int32, format, xtype uint32, pix unsafe.Pointer)
+
+	// sync objects
+	FenceSync(condition, flags uint32) uintptr
+	ClientWaitSync(sync uintptr, flags uint32, timeout uint64) uint32
+	DeleteSync(sync uintptr)
+
+	// shaders and programs
+	CreateShader(xtype uint32) uint32
+	DeleteShader(id uint32)
+	ShaderSource(id uint32, src string)
+	CompileShader(id uint32)
+	GetShaderi(id, pname uint32) int32
+	GetShaderInfoLog(id uint32) string
+	CreateProgram() uint32
+	DeleteProgram(id uint32)
+	AttachShader(program, shader uint32)
+	LinkProgram(id uint32)
+	UseProgram(id uint32)
+	GetProgrami(id, pname uint32) int32
+	GetProgramInfoLog(id uint32) string
+	GetActiveAttrib(program, index uint32) (name string, size int32, xtype uint32)
+	GetActiveUniform(program, index uint32) (name string, size int32, xtype uint32)
+	GetAttribLocation(program uint32, name string) int32
+	GetUniformLocation(program uint32, name string) int32
+	GetActiveUniformsiv(program uint32, indices []uint32, pname uint32, params []int32)
+
+	// uniform blocks
+	GetUniformBlockIndex(program uint32, name string) uint32
+	GetActiveUniformBlockName(program, index uint32) string
+	GetActiveUniformBlockiv(program, index, pname uint32, params []int32)
+	UniformBlockBinding(program, index, binding uint32)
+	BindBufferBase(target, index, id uint32)
+
+	// uniforms of the program in use
+	Uniform1i(loc, v int32)
+	Uniform1f(loc int32, v float32)
+	Uniform2f(loc int32, v0, v1 float32)
+	Uniform3f(loc int32, v0, v1, v2 float32)
+	Uniform4f(loc int32, v0, v1, v2, v3 float32)
+	UniformMatrix4fv(loc int32, transpose bool, m []float32)
+	// arrays, the count is the length of v divided by the size
+	Uniform1fv(loc int32, v []float32)
+	Uniform2fv(loc int32, v []float32)
+	Uniform3fv(loc int32, v []float32)
+	Uniform4fv(loc int32, v []float32)
+	Uniform1iv(loc int32, v []int32)
+	Uniform1uiv(loc int32, v []uint32)
+	UniformMatrix3fv(loc int32, transpose bool, m []float32)
+}
+
+// tDevice - a device and the state the package keeps of it. Objects keep
+// the tDevice they were created with.
+type tDevice struct {
+	IDevice
+	// activeProgram is the program set by TProgram.Use
+	activeProgram *TProgram
+}
+
+var (
+	devicesMu sync.Mutex
+	devices   = map[IDevice]*tDevice{}
+	// dev is the device new objects are created with
+	dev = wrapDevice(NewGLESDevice())
+)
+
+// wrapDevice returns the same tDevice for the same device, so objects
+// created with it share the program in use.
+func wrapDevice(d IDevice) *tDevice {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	ret, ok := devices[d]
+	if !ok {
+		ret = &tDevice{IDevice: d}
+		devices[d] = ret
+	}
+	return ret
+}
+
+// Device - returns the device new objects are created with.
+func Device() IDevice {
+	return dev.IDevice
+}
+
+// SetDevice - replaces the device new objects are created with and returns
+// the previous one, e.g. to check the commands of a TRecorder in a test.
+// Objects keep rendering with the device they were created with, so the
+// previous device may be restored as soon as they exist.
+func SetDevice(d IDevice) IDevice {
+	prev := dev.IDevice
+	dev = wrapDevice(d)
+	return prev
+}
+
+func (o *tDevice) getInteger(pname uint32) int32 {
+	ret := []int32{0}
+	o.GetIntegerv(pname, ret)
+	return ret[0]
+}
+
+// ActiveProgram - returns the program in use on the current device, nil if
+// there is none.
+func ActiveProgram() *TProgram {
+	return dev.activeProgram
+}
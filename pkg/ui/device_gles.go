@@ -0,0 +1,428 @@
+package ui
+
+import (
+	"unsafe"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+)
+
+// TGLESDevice - renders with the OpenGL ES context current on the thread.
+type TGLESDevice struct{}
+
+// NewGLESDevice -
+func NewGLESDevice() *TGLESDevice {
+	return &TGLESDevice{}
+}
+
+// Init - loads the functions of the current context.
+func (o *TGLESDevice) Init() error { return gl.Init() }
+
+// GetError -
+func (o *TGLESDevice) GetError() uint32 { return gl.GetError() }
+
+// GetString -
+func (o *TGLESDevice) GetString(name uint32) string {
+	s := gl.GetString(name)
+	if s == nil {
+		return ""
+	}
+	return gl.GoStr(s)
+}
+
+// GetIntegerv -
+func (o *TGLESDevice) GetIntegerv(pname uint32, data []int32) { gl.GetIntegerv(pname, &data[0]) }
+
+// Finish -
+func (o *TGLESDevice) Finish() { gl.Finish() }
+
+// Enable -
+func (o *TGLESDevice) Enable(capability uint32) { gl.Enable(capability) }
+
+// Disable -
+func (o *TGLESDevice) Disable(capability uint32) { gl.Disable(capability) }
+
+// BlendFunc -
+func (o *TGLESDevice) BlendFunc(sfactor, dfactor uint32) { gl.BlendFunc(sfactor, dfactor) }
+
+// ColorMask -
+func (o *TGLESDevice) ColorMask(r, g, b, a bool) { gl.ColorMask(r, g, b, a) }
+
+// StencilFunc -
+func (o *TGLESDevice) StencilFunc(fn uint32, ref int32, mask uint32) { gl.StencilFunc(fn, ref, mask) }
+
+// StencilMask -
+func (o *TGLESDevice) StencilMask(mask uint32) { gl.StencilMask(mask) }
+
+// StencilOp -
+func (o *TGLESDevice) StencilOp(fail, zfail, zpass uint32) { gl.StencilOp(fail, zfail, zpass) }
+
+// StencilOpSeparate -
+func (o *TGLESDevice) StencilOpSeparate(face, fail, zfail, zpass uint32) {
+	gl.StencilOpSeparate(face, fail, zfail, zpass)
+}
+
+// Viewport -
+func (o *TGLESDevice) Viewport(x, y, w, h int32) { gl.Viewport(x, y, w, h) }
+
+// ClearColor -
+func (o *TGLESDevice) ClearColor(r, g, b, a float32) { gl.ClearColor(r, g, b, a) }
+
+// Clear -
+func (o *TGLESDevice) Clear(mask uint32) { gl.Clear(mask) }
+
+// GenBuffer -
+func (o *TGLESDevice) GenBuffer() uint32 {
+	id := uint32(0)
+	gl.GenBuffers(1, &id)
+	return id
+}
+
+// DeleteBuffer -
+func (o *TGLESDevice) DeleteBuffer(id uint32) { gl.DeleteBuffers(1, &id) }
+
+// BindBuffer -
+func (o *TGLESDevice) BindBuffer(target, id uint32) { gl.BindBuffer(target, id) }
+
+// BufferData -
+func (o *TGLESDevice) BufferData(target uint32, size int, data unsafe.Pointer, usage uint32) {
+	gl.BufferData(target, size, data, usage)
+}
+
+// BufferSubData -
+func (o *TGLESDevice) BufferSubData(target uint32, offset, size int, data unsafe.Pointer) {
+	gl.BufferSubData(target, offset, size, data)
+}
+
+// MapBufferRange -
+func (o *TGLESDevice) MapBufferRange(target uint32, offset, size int, access uint32) unsafe.Pointer {
+	return gl.MapBufferRange(target, offset, size, access)
+}
+
+// UnmapBuffer -
+func (o *TGLESDevice) UnmapBuffer(target uint32) bool { return gl.UnmapBuffer(target) }
+
+// GenVertexArray -
+func (o *TGLESDevice) GenVertexArray() uint32 {
+	id := uint32(0)
+	gl.GenVertexArrays(1, &id)
+	return id
+}
+
+// DeleteVertexArray -
+func (o *TGLESDevice) DeleteVertexArray(id uint32) { gl.DeleteVertexArrays(1, &id) }
+
+// BindVertexArray -
+func (o *TGLESDevice) BindVertexArray(id uint32) { gl.BindVertexArray(id) }
+
+// EnableVertexAttribArray -
+func (o *TGLESDevice) EnableVertexAttribArray(index uint32) { gl.EnableVertexAttribArray(index) }
+
+// VertexAttribPointer -
+func (o *TGLESDevice) VertexAttribPointer(index uint32, size int32, xtype uint32, normalized bool, stride int32, offset int) {
+	gl.VertexAttribPointer(index, size, xtype, normalized, stride, gl.PtrOffset(offset))
+}
+
+// VertexAttribIPointer -
+func (o *TGLESDevice) VertexAttribIPointer(index uint32, size int32, xtype uint32, stride int32, offset int) {
+	gl.VertexAttribIPointer(index, size, xtype, stride, gl.PtrOffset(offset))
+}
+
+// DrawArrays -
+func (o *TGLESDevice) DrawArrays(mode uint32, first, count int32) { gl.DrawArrays(mode, first, count) }
+
+// DrawElements -
+func (o *TGLESDevice) DrawElements(mode uint32, count int32, xtype uint32, offset int) {
+	gl.DrawElements(mode, count, xtype, gl.PtrOffset(offset))
+}
+
+// GenTexture -
+func (o *TGLESDevice) GenTexture() uint32 {
+	id := uint32(0)
+	gl.GenTextures(1, &id)
+	return id
+}
+
+// DeleteTexture -
+func (o *TGLESDevice) DeleteTexture(id uint32) { gl.DeleteTextures(1, &id) }
+
+// ActiveTexture -
+func (o *TGLESDevice) ActiveTexture(unit uint32) { gl.ActiveTexture(unit) }
+
+// BindTexture -
+func (o *TGLESDevice) BindTexture(target, id uint32) { gl.BindTexture(target, id) }
+
+// TexImage2D -
+func (o *TGLESDevice) TexImage2D(target uint32, level, internalFormat, w, h int32, format, xtype uint32, pix unsafe.Pointer) {
+	gl.TexImage2D(target, level, internalFormat, w, h, 0, format, xtype, pix)
+}
+
+// TexSubImage2D -
+func (o *TGLESDevice) TexSubImage2D(target uint32, level, x, y, w, h int32, format, xtype uint32, pix unsafe.Pointer) {
+	gl.TexSubImage2D(target, level, x, y, w, h, format, xtype, pix)
+}
+
+// TexParameteri -
+func (o *TGLESDevice) TexParameteri(target, pname uint32, param int32) {
+	gl.TexParameteri(target, pname, param)
+}
+
+// GenerateMipmap -
+func (o *TGLESDevice) GenerateMipmap(target uint32) { gl.GenerateMipmap(target) }
+
+// PixelStorei -
+func (o *TGLESDevice) PixelStorei(pname uint32, param int32) { gl.PixelStorei(pname, param) }
+
+// GenFramebuffer -
+func (o *TGLESDevice) GenFramebuffer() uint32 {
+	id := uint32(0)
+	gl.GenFramebuffers(1, &id)
+	return id
+}
+
+// DeleteFramebuffer -
+func (o *TGLESDevice) DeleteFramebuffer(id uint32) { gl.DeleteFramebuffers(1, &id) }
+
+// BindFramebuffer -
+func (o *TGLESDevice) BindFramebuffer(target, id uint32) { gl.BindFramebuffer(target, id) }
+
+// CheckFramebufferStatus -
+func (o *TGLESDevice) CheckFramebufferStatus(target uint32) uint32 {
+	return gl.CheckFramebufferStatus(target)
+}
+
+// FramebufferTexture2D -
+func (o *TGLESDevice) FramebufferTexture2D(target, attachment, texTarget, texture uint32, level int32) {
+	gl.FramebufferTexture2D(target, attachment, texTarget, texture, level)
+}
+
+// FramebufferRenderbuffer -
+func (o *TGLESDevice) FramebufferRenderbuffer(target, attachment, rbTarget, renderbuffer uint32) {
+	gl.FramebufferRenderbuffer(target, attachment, rbTarget, renderbuffer)
+}
+
+// GetFramebufferAttachmentParameteri -
+func (o *TGLESDevice) GetFramebufferAttachmentParameteri(target, attachment, pname uint32) int32 {
+	ret := int32(0)
+	gl.GetFramebufferAttachmentParameteriv(target, attachment, pname, &ret)
+	return ret
+}
+
+// GenRenderbuffer -
+func (o *TGLESDevice) GenRenderbuffer() uint32 {
+	id := uint32(0)
+	gl.GenRenderbuffers(1, &id)
+	return id
+}
+
+// DeleteRenderbuffer -
+func (o *TGLESDevice) DeleteRenderbuffer(id uint32) { gl.DeleteRenderbuffers(1, &id) }
+
+// BindRenderbuffer -
+func (o *TGLESDevice) BindRenderbuffer(target, id uint32) { gl.BindRenderbuffer(target, id) }
+
+// RenderbufferStorage -
+func (o *TGLESDevice) RenderbufferStorage(target, internalFormat uint32, w, h int32) {
+	gl.RenderbufferStorage(target, internalFormat, w, h)
+}
+
+// ReadBuffer -
+func (o *TGLESDevice) ReadBuffer(src uint32) { gl.ReadBuffer(src) }
+
+// ReadPixels -
+func (o *TGLESDevice) ReadPixels(x, y, w, h int32, format, xtype uint32, pix unsafe.Pointer) {
+	gl.ReadPixels(x, y, w, h, format, xtype, pix)
+}
+
+// FenceSync -
+func (o *TGLESDevice) FenceSync(condition, flags uint32) uintptr {
+	return gl.FenceSync(condition, flags)
+}
+
+// ClientWaitSync -
+func (o *TGLESDevice) ClientWaitSync(sync uintptr, flags uint32, timeout uint64) uint32 {
+	return gl.ClientWaitSync(sync, flags, timeout)
+}
+
+// DeleteSync -
+func (o *TGLESDevice) DeleteSync(sync uintptr) { gl.DeleteSync(sync) }
+
+// CreateShader -
+func (o *TGLESDevice) CreateShader(xtype uint32) uint32 { return gl.CreateShader(xtype) }
+
+// DeleteShader -
+func (o *TGLESDevice) DeleteShader(id uint32) { gl.DeleteShader(id) }
+
+// ShaderSource -
+func (o *TGLESDevice) ShaderSource(id uint32, src string) {
+	csrc, free := gl.Strs(src)
+	gl.ShaderSource(id, 1, csrc, nil)
+	free()
+}
+
+// CompileShader -
+func (o *TGLESDevice) CompileShader(id uint32) { gl.CompileShader(id) }
+
+// GetShaderi -
+func (o *TGLESDevice) GetShaderi(id, pname uint32) int32 {
+	ret := int32(0)
+	gl.GetShaderiv(id, pname, &ret)
+	return ret
+}
+
+// GetShaderInfoLog -
+func (o *TGLESDevice) GetShaderInfoLog(id uint32) string {
+	n := o.GetShaderi(id, gl.INFO_LOG_LENGTH)
+	if n <= 0 {
+		return ""
+	}
+	buf := make([]uint8, n+1)
+	gl.GetShaderInfoLog(id, n, nil, &buf[0])
+	return gl.GoStr(&buf[0])
+}
+
+// CreateProgram -
+func (o *TGLESDevice) CreateProgram() uint32 { return gl.CreateProgram() }
+
+// DeleteProgram -
+func (o *TGLESDevice) DeleteProgram(id uint32) { gl.DeleteProgram(id) }
+
+// AttachShader -
+func (o *TGLESDevice) AttachShader(program, shader uint32) { gl.AttachShader(program, shader) }
+
+// LinkProgram -
+func (o *TGLESDevice) LinkProgram(id uint32) { gl.LinkProgram(id) }
+
+// UseProgram -
+func (o *TGLESDevice) UseProgram(id uint32) { gl.UseProgram(id) }
+
+// GetProgrami -
+func (o *TGLESDevice) GetProgrami(id, pname uint32) int32 {
+	ret := int32(0)
+	gl.GetProgramiv(id, pname, &ret)
+	return ret
+}
+
+// GetProgramInfoLog -
+func (o *TGLESDevice) GetProgramInfoLog(id uint32) string {
+	n := o.GetProgrami(id, gl.INFO_LOG_LENGTH)
+	if n <= 0 {
+		return ""
+	}
+	buf := make([]uint8, n+1)
+	gl.GetProgramInfoLog(id, n, nil, &buf[0])
+	return gl.GoStr(&buf[0])
+}
+
+// GetActiveAttrib -
+func (o *TGLESDevice) GetActiveAttrib(program, index uint32) (string, int32, uint32) {
+	return o.getActive(gl.GetActiveAttrib, gl.ACTIVE_ATTRIBUTE_MAX_LENGTH, program, index)
+}
+
+// GetActiveUniform -
+func (o *TGLESDevice) GetActiveUniform(program, index uint32) (string, int32, uint32) {
+	return o.getActive(gl.GetActiveUniform, gl.ACTIVE_UNIFORM_MAX_LENGTH, program, index)
+}
+
+func (o *TGLESDevice) getActive(fn func(uint32, uint32, int32, *int32, *int32, *uint32, *uint8),
+	maxLength uint32, program, index uint32) (string, int32, uint32) {
+	n := o.GetProgrami(program, maxLength)
+	if n <= 0 {
+		n = 1
+	}
+	buf := make([]uint8, n)
+	length, size, xtype := int32(0), int32(0), uint32(0)
+	fn(program, index, n, &length, &size, &xtype, &buf[0])
+	return string(buf[:length]), size, xtype
+}
+
+// GetAttribLocation -
+func (o *TGLESDevice) GetAttribLocation(program uint32, name string) int32 {
+	return gl.GetAttribLocation(program, gl.Str(name+"\x00"))
+}
+
+// GetUniformLocation -
+func (o *TGLESDevice) GetUniformLocation(program uint32, name string) int32 {
+	return gl.GetUniformLocation(program, gl.Str(name+"\x00"))
+}
+
+// GetActiveUniformsiv - params must hold a value per index.
+func (o *TGLESDevice) GetActiveUniformsiv(program uint32, indices []uint32, pname uint32, params []int32) {
+	if len(indices) == 0 {
+		return
+	}
+	gl.GetActiveUniformsiv(program, int32(len(indices)), &indices[0], pname, &params[0])
+}
+
+// GetUniformBlockIndex -
+func (o *TGLESDevice) GetUniformBlockIndex(program uint32, name string) uint32 {
+	return gl.GetUniformBlockIndex(program, gl.Str(name+"\x00"))
+}
+
+// GetActiveUniformBlockName -
+func (o *TGLESDevice) GetActiveUniformBlockName(program, index uint32) string {
+	n := o.GetProgrami(program, gl.ACTIVE_UNIFORM_BLOCK_MAX_NAME_LENGTH)
+	if n <= 0 {
+		n = 1
+	}
+	buf := make([]uint8, n)
+	length := int32(0)
+	gl.GetActiveUniformBlockName(program, index, n, &length, &buf[0])
+	return string(buf[:length])
+}
+
+// GetActiveUniformBlockiv - params must be large enough for the values of
+// pname, e.g. UNIFORM_BLOCK_ACTIVE_UNIFORM_INDICES returns one per member.
+func (o *TGLESDevice) GetActiveUniformBlockiv(program, index, pname uint32, params []int32) {
+	gl.GetActiveUniformBlockiv(program, index, pname, &params[0])
+}
+
+// UniformBlockBinding -
+func (o *TGLESDevice) UniformBlockBinding(program, index, binding uint32) {
+	gl.UniformBlockBinding(program, index, binding)
+}
+
+// BindBufferBase -
+func (o *TGLESDevice) BindBufferBase(target, index, id uint32) { gl.BindBufferBase(target, index, id) }
+
+// Uniform1i -
+func (o *TGLESDevice) Uniform1i(loc, v int32) { gl.Uniform1i(loc, v) }
+
+// Uniform1f -
+func (o *TGLESDevice) Uniform1f(loc int32, v float32) { gl.Uniform1f(loc, v) }
+
+// Uniform2f -
+func (o *TGLESDevice) Uniform2f(loc int32, v0, v1 float32) { gl.Uniform2f(loc, v0, v1) }
+
+// Uniform3f -
+func (o *TGLESDevice) Uniform3f(loc int32, v0, v1, v2 float32) { gl.Uniform3f(loc, v0, v1, v2) }
+
+// Uniform4f -
+func (o *TGLESDevice) Uniform4f(loc int32, v0, v1, v2, v3 float32) { gl.Uniform4f(loc, v0, v1, v2, v3) }
+
+// UniformMatrix4fv - m holds one or more matrices.
+func (o *TGLESDevice) UniformMatrix4fv(loc int32, transpose bool, m []float32) {
+	gl.UniformMatrix4fv(loc, int32(len(m)/16), transpose, &m[0])
+}
+
+// Uniform1fv -
+func (o *TGLESDevice) Uniform1fv(loc int32, v []float32) { gl.Uniform1fv(loc, int32(len(v)), &v[0]) }
+
+// Uniform2fv -
+func (o *TGLESDevice) Uniform2fv(loc int32, v []float32) { gl.Uniform2fv(loc, int32(len(v)/2), &v[0]) }
+
+// Uniform3fv -
+func (o *TGLESDevice) Uniform3fv(loc int32, v []float32) { gl.Uniform3fv(loc, int32(len(v)/3), &v[0]) }
+
+// Uniform4fv -
+func (o *TGLESDevice) Uniform4fv(loc int32, v []float32) { gl.Uniform4fv(loc, int32(len(v)/4), &v[0]) }
+
+// Uniform1iv -
+func (o *TGLESDevice) Uniform1iv(loc int32, v []int32) { gl.Uniform1iv(loc, int32(len(v)), &v[0]) }
+
+// Uniform1uiv -
+func (o *TGLESDevice) Uniform1uiv(loc int32, v []uint32) { gl.Uniform1uiv(loc, int32(len(v)), &v[0]) }
+
+// UniformMatrix3fv - m holds one or more matrices.
+func (o *TGLESDevice) UniformMatrix3fv(loc int32, transpose bool, m []float32) {
+	gl.UniformMatrix3fv(loc, int32(len(m)/9), transpose, &m[0])
+}
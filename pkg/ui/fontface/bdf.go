@@ -0,0 +1,129 @@
+package fontface
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// NewBDFFromReader - loads a Glyph Bitmap Distribution Format (.bdf) font.
+// Encodings are treated as unicode code points.
+func NewBDFFromReader(r io.Reader) (*TFontFace, error) {
+	glyphs, err := parseBDF(r)
+	if err != nil {
+		return nil, err
+	}
+	return newFromBitmaps(glyphs, image.Rectangle{})
+}
+
+func parseBDF(r io.Reader) ([]tBitmap, error) {
+	sc := bufio.NewScanner(r)
+	line := 0
+	errorf := func(format string, args ...interface{}) error {
+		return fmt.Errorf("bdf: line %v: %v", line, fmt.Sprintf(format, args...))
+	}
+	ints := func(fields []string, n int) ([]int, error) {
+		if len(fields) < n+1 {
+			return nil, errorf("%v wants %v numbers", fields[0], n)
+		}
+		ret := make([]int, n)
+		for i := range ret {
+			v, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				return nil, errorf("%v: %v", fields[0], err)
+			}
+			ret[i] = v
+		}
+		return ret, nil
+	}
+
+	ret := []tBitmap{}
+	fontAdvance := 0
+	inChar := false
+	encoding := -1
+	advance := 0
+	bbx := []int{0, 0, 0, 0}
+	for sc.Scan() {
+		line++
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "STARTCHAR":
+			inChar = true
+			encoding = -1
+			advance = fontAdvance
+		case "ENCODING":
+			v, err := ints(fields, 1)
+			if err != nil {
+				return nil, err
+			}
+			encoding = v[0]
+		case "DWIDTH":
+			v, err := ints(fields, 1)
+			if err != nil {
+				return nil, err
+			}
+			if inChar {
+				advance = v[0]
+			} else {
+				fontAdvance = v[0]
+			}
+		case "BBX":
+			v, err := ints(fields, 4)
+			if err != nil {
+				return nil, err
+			}
+			if v[0] < 0 || v[1] < 0 {
+				return nil, errorf("BBX: negative size")
+			}
+			bbx = v
+		case "BITMAP":
+			if !inChar {
+				return nil, errorf("BITMAP outside of a glyph")
+			}
+			w, h := bbx[0], bbx[1]
+			stride := (w + 7) / 8
+			data := make([]byte, 0, h*stride)
+			for y := 0; y < h; y++ {
+				if !sc.Scan() {
+					return nil, errorf("unexpected end of file in BITMAP")
+				}
+				line++
+				row, err := hex.DecodeString(strings.TrimSpace(sc.Text()))
+				if err != nil {
+					return nil, errorf("BITMAP: %v", err)
+				}
+				if len(row) < stride {
+					return nil, errorf("BITMAP: want %v bytes per row but got %v", stride, len(row))
+				}
+				data = append(data, row[:stride]...)
+			}
+			if encoding < 0 {
+				// glyphs without a standard encoding are not addressable
+				continue
+			}
+			mask, err := bitsToAlpha(data, w, h, stride)
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			ret = append(ret, tBitmap{
+				r:       rune(encoding),
+				mask:    mask,
+				origin:  image.Pt(bbx[2], -(bbx[3] + h)),
+				advance: advance,
+			})
+		case "ENDCHAR":
+			inChar = false
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,71 @@
+package fontface
+
+import (
+	"fmt"
+	"image"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// tBitmap is a glyph of a bitmap font.
+type tBitmap struct {
+	r       rune
+	mask    *image.Alpha
+	origin  image.Point // top left corner of the mask relative to the dot, Y axis down
+	advance int
+}
+
+// newFromBitmaps packs ready made glyph masks into an atlas. When a rune
+// occurs several times the first glyph wins. The line box is the union of
+// bounds and all the glyphs.
+func newFromBitmaps(glyphs []tBitmap, bounds image.Rectangle) (*TFontFace, error) {
+	if len(glyphs) == 0 {
+		return nil, fmt.Errorf("font has no glyphs")
+	}
+	slice := []tMask{}
+	seen := map[rune]bool{}
+	maxAdvance := fixed.Int26_6(-1)
+	iBounds := bounds
+	for _, g := range glyphs {
+		if seen[g.r] {
+			continue
+		}
+		seen[g.r] = true
+		dr := g.mask.Bounds().Sub(g.mask.Bounds().Min).Add(g.origin)
+		adv := fixed.I(g.advance)
+		if adv > maxAdvance {
+			maxAdvance = adv
+		}
+		iBounds = iBounds.Union(dr)
+		slice = append(slice, tMask{
+			r:         g.r,
+			destRect:  dr,
+			mask:      g.mask,
+			maskPoint: g.mask.Bounds().Min,
+			advance:   adv,
+		})
+	}
+	texW, texH := atlasSize(slice)
+	return pack(slice, texW, texH, maxAdvance, iBounds)
+}
+
+// bitsToAlpha converts a 1 bit per pixel, most significant bit first image
+// with rows of stride bytes.
+func bitsToAlpha(data []byte, w, h, stride int) (*image.Alpha, error) {
+	if w < 0 || h < 0 || stride < (w+7)/8 {
+		return nil, fmt.Errorf("bad bitmap geometry %vx%v stride %v", w, h, stride)
+	}
+	if len(data) < h*stride {
+		return nil, fmt.Errorf("bitmap data is too short: want %v bytes but got %v", h*stride, len(data))
+	}
+	ret := image.NewAlpha(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		row := data[y*stride:]
+		for x := 0; x < w; x++ {
+			if row[x/8]&(0x80>>uint(x%8)) != 0 {
+				ret.Pix[y*ret.Stride+x] = 0xff
+			}
+		}
+	}
+	return ret, nil
+}
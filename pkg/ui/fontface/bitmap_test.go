@@ -0,0 +1,207 @@
+package fontface
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"strings"
+	"testing"
+)
+
+// maskRows draws a mask as rows of '#' and '.'.
+func maskRows(mask *image.Alpha) []string {
+	b := mask.Bounds()
+	ret := []string{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := []byte{}
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := byte('.')
+			if mask.AlphaAt(x, y).A != 0 {
+				c = '#'
+			}
+			row = append(row, c)
+		}
+		ret = append(ret, string(row))
+	}
+	return ret
+}
+
+// checkGlyph checks the only glyph of glyphs.
+func checkGlyph(t *testing.T, glyphs []tBitmap, r rune, origin image.Point, advance int, rows ...string) {
+	t.Helper()
+	if len(glyphs) != 1 {
+		t.Fatalf("%v glyphs, want 1", len(glyphs))
+	}
+	g := glyphs[0]
+	if g.r != r || g.origin != origin || g.advance != advance {
+		t.Errorf("glyph %q at %v advance %v, want %q at %v advance %v",
+			g.r, g.origin, g.advance, r, origin, advance)
+	}
+	if got := maskRows(g.mask); strings.Join(got, "|") != strings.Join(rows, "|") {
+		t.Errorf("mask %v, want %v", got, rows)
+	}
+}
+
+const testBDF = `STARTFONT 2.1
+FONTBOUNDINGBOX 8 8 0 -2
+CHARS 2
+STARTCHAR A
+ENCODING 65
+DWIDTH 6 0
+BBX 5 3 1 -1
+BITMAP
+F8
+88
+F8
+ENDCHAR
+STARTCHAR unencoded
+ENCODING -1
+BBX 1 1 0 0
+BITMAP
+80
+ENDCHAR
+ENDFONT
+`
+
+func TestParseBDF(t *testing.T) {
+	glyphs, err := parseBDF(strings.NewReader(testBDF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGlyph(t, glyphs, 'A', image.Pt(1, -2), 6, "#####", "#...#", "#####")
+
+	tests := []struct {
+		name string
+		src  string
+		err  string
+	}{
+		{"truncated bitmap", testBDF[:strings.Index(testBDF, "88")], "unexpected end of file"},
+		{"negative width", strings.Replace(testBDF, "BBX 5 3", "BBX -5 3", 1), "BBX: negative size"},
+		{"negative height", strings.Replace(testBDF, "BBX 5 3", "BBX 5 -3", 1), "BBX: negative size"},
+		{"short BBX", strings.Replace(testBDF, "BBX 5 3 1 -1", "BBX 5 3", 1), "BBX wants 4 numbers"},
+		{"short row", strings.Replace(testBDF, "BBX 5 3", "BBX 9 3", 1), "want 2 bytes per row"},
+		{"bad hex", strings.Replace(testBDF, "88", "8G", 1), "BITMAP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseBDF(strings.NewReader(tt.src))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// testPCF builds a font with the glyph 'B', 3x2 pixels with the rows "#.#"
+// and ".#.", stored least significant bit and byte first, padded to 4 bytes.
+func testPCF() []byte {
+	le := binary.LittleEndian
+	metrics := []byte{0, 1, 0, 0, 1, 0} // compressed format, 1 metric
+	metrics = append(metrics, 0x80, 0x83, 0x84, 0x82, 0x80)
+	bitmaps := []byte{2, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0} // pad 4; 1 glyph at 0
+	sizes := make([]byte, 16)
+	le.PutUint32(sizes[8:], 8)
+	bitmaps = append(bitmaps, sizes...)
+	bitmaps = append(bitmaps, 0x05, 0, 0, 0, 0x02, 0, 0, 0)
+	encodings := []byte{0, 0, 0, 0, 'B', 0, 'B', 0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+	tables := [][]byte{metrics, bitmaps, encodings}
+	types := []uint32{pcfMetrics, pcfBitmaps, pcfBDFEncodings}
+	ret := make([]byte, 8+pcfTOCEntrySize*len(tables))
+	copy(ret, "\x01fcp")
+	le.PutUint32(ret[4:], uint32(len(tables)))
+	for i, table := range tables {
+		p := ret[8+pcfTOCEntrySize*i:]
+		le.PutUint32(p, types[i])
+		le.PutUint32(p[4:], le.Uint32(table))
+		le.PutUint32(p[8:], uint32(len(table)))
+		le.PutUint32(p[12:], uint32(len(ret)))
+		ret = append(ret, table...)
+	}
+	return ret
+}
+
+func TestParsePCF(t *testing.T) {
+	data := testPCF()
+	glyphs, err := parsePCF(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGlyph(t, glyphs, 'B', image.Pt(0, -2), 4, "#.#", ".#.")
+
+	for n := 0; n < len(data); n++ {
+		if _, err := parsePCF(data[:n]); err == nil {
+			t.Errorf("parsed %v of %v bytes", n, len(data))
+		}
+	}
+	bad := append([]byte(nil), data...)
+	bad[len(bad)-2] = 5 // the glyph index of 'B'
+	if _, err := parsePCF(bad); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("got %v for a bad glyph index", err)
+	}
+}
+
+// testPSF2 builds a font of two 10x3 glyphs, the first one mapped to 'Ж'
+// and to the sequence "ab", which is skipped, the second one to 'z'.
+func testPSF2() []byte {
+	le := binary.LittleEndian
+	ret := make([]byte, psf2HeaderSize)
+	copy(ret, psf2Magic)
+	le.PutUint32(ret[8:], psf2HeaderSize)
+	le.PutUint32(ret[12:], psf2HasUnicode)
+	le.PutUint32(ret[16:], 2)
+	le.PutUint32(ret[20:], 2*3)
+	le.PutUint32(ret[24:], 3)
+	le.PutUint32(ret[28:], 10)
+	ret = append(ret, 0xff, 0xc0, 0x80, 0x40, 0xff, 0xc0)
+	ret = append(ret, 0, 0, 0, 0, 0, 0)
+	return append(ret, "Ж\xfeab\xffz\xff"...)
+}
+
+func TestParsePSF(t *testing.T) {
+	psf1 := []byte{0x36, 0x04, 0, 2}
+	for i := 0; i < psfDefaultCount; i++ {
+		psf1 = append(psf1, byte(i), 0x81)
+	}
+	face, err := NewPSFFromReader(bytes.NewReader(psf1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(face.CharMap) != psfDefaultCount {
+		t.Errorf("psf1: %v glyphs, want %v", len(face.CharMap), psfDefaultCount)
+	}
+
+	data := testPSF2()
+	glyphs, err := parsePSF2(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(glyphs) != 2 || glyphs[1].r != 'z' {
+		t.Fatalf("psf2: glyphs %+v, want 'Ж' and 'z'", glyphs)
+	}
+	checkGlyph(t, glyphs[:1], 'Ж', image.Pt(0, -3), 10, "##########", "#........#", "##########")
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"psf1 truncated", psf1[:100], "truncated glyph data"},
+		{"psf1 empty glyphs", []byte{0x36, 0x04, 0, 0}, "bad glyph size"},
+		{"psf2 truncated", data[:psf2HeaderSize+7], "truncated glyph data"},
+		{"psf2 header", data[:psf2HeaderSize-1], "bad signature"},
+		{"psf2 char size", func() []byte {
+			bad := append([]byte(nil), data...)
+			bad[20] = 5
+			return bad
+		}(), "bad header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPSFFromReader(bytes.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
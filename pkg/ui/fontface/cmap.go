@@ -0,0 +1,257 @@
+package fontface
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"unicode"
+)
+
+// Coverage - returns the sorted code point ranges mapped to glyphs by the
+// cmap table of the font. data is a TrueType/OpenType font, a collection
+// (index selects the face) or a WOFF file.
+func Coverage(data []byte, index int) ([]TRange, error) {
+	if len(data) >= 4 && string(data[:4]) == "wOFF" {
+		var err error
+		data, err = decodeWOFF(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	offsets, err := sfntOffsets(data)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(offsets) {
+		return nil, fmt.Errorf("face index %v is out of range 0..%v", index, len(offsets)-1)
+	}
+	return coverageAt(data, offsets[index])
+}
+
+// sfntOffsets returns the offsets of the table directories of the faces, a
+// single zero one for non collections.
+func sfntOffsets(data []byte) ([]int, error) {
+	if len(data) < 4 || string(data[:4]) != "ttcf" {
+		return []int{0}, nil
+	}
+	if len(data) < 12 {
+		return nil, fmt.Errorf("truncated collection header")
+	}
+	n := int(binary.BigEndian.Uint32(data[8:]))
+	if n == 0 || len(data) < 12+n*4 {
+		return nil, fmt.Errorf("truncated collection header")
+	}
+	ret := make([]int, n)
+	for i := range ret {
+		ret[i] = int(binary.BigEndian.Uint32(data[12+i*4:]))
+	}
+	return ret, nil
+}
+
+func coverageAt(data []byte, offset int) ([]TRange, error) {
+	tables, err := parseSFNTTables(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	cmap, err := tables.table("cmap", 4)
+	if err != nil {
+		return nil, err
+	}
+	return cmapCoverage(cmap)
+}
+
+// cmapSubtableScore ranks the encodings the way sfnt picks them: full
+// Unicode, then BMP only, then Microsoft Symbol. Negative means unusable.
+func cmapSubtableScore(platform, encoding, format uint16) int {
+	switch format {
+	case 0, 4, 6, 12, 13:
+	default:
+		return -1
+	}
+	switch {
+	case platform == 0 && encoding >= 4, platform == 3 && encoding == 10:
+		return 2
+	case platform == 0, platform == 3 && encoding == 1:
+		return 1
+	case platform == 3 && encoding == 0:
+		return 0
+	}
+	return -1
+}
+
+func cmapCoverage(cmap []byte) ([]TRange, error) {
+	be := binary.BigEndian
+	numTables := int(be.Uint16(cmap[2:]))
+	if len(cmap) < 4+numTables*8 {
+		return nil, fmt.Errorf("cmap: truncated encoding records")
+	}
+	var sub []byte
+	best := -1
+	for i := 0; i < numTables; i++ {
+		rec := cmap[4+i*8:]
+		offset := int(be.Uint32(rec[4:]))
+		if offset+2 > len(cmap) {
+			return nil, fmt.Errorf("cmap: subtable is out of bounds")
+		}
+		score := cmapSubtableScore(be.Uint16(rec), be.Uint16(rec[2:]), be.Uint16(cmap[offset:]))
+		if score > best {
+			best, sub = score, cmap[offset:]
+		}
+	}
+	if sub == nil {
+		return nil, fmt.Errorf("cmap: no supported unicode subtable")
+	}
+
+	ret := []TRange{}
+	var err error
+	switch be.Uint16(sub) {
+	case 0:
+		ret, err = cmapCoverage0(sub)
+	case 4:
+		ret, err = cmapCoverage4(sub)
+	case 6:
+		ret, err = cmapCoverage6(sub)
+	case 12, 13:
+		ret, err = cmapCoverage12(sub)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return normRanges(ret), nil
+}
+
+// tRuns collects code points in increasing order into ranges.
+type tRuns []TRange
+
+func (o *tRuns) add(r rune) {
+	if n := len(*o); n > 0 && (*o)[n-1].End == r {
+		(*o)[n-1].End++
+		return
+	}
+	*o = append(*o, TRange{r, r + 1})
+}
+
+func cmapCoverage0(sub []byte) ([]TRange, error) {
+	if len(sub) < 6+256 {
+		return nil, fmt.Errorf("cmap: truncated format 0 subtable")
+	}
+	runs := tRuns{}
+	for c := 0; c < 256; c++ {
+		if sub[6+c] != 0 {
+			runs.add(rune(c))
+		}
+	}
+	return runs, nil
+}
+
+func cmapCoverage4(sub []byte) ([]TRange, error) {
+	be := binary.BigEndian
+	if len(sub) < 14 {
+		return nil, fmt.Errorf("cmap: truncated format 4 subtable")
+	}
+	segX2 := int(be.Uint16(sub[6:]))
+	ends := 14
+	starts := ends + segX2 + 2
+	deltas := starts + segX2
+	rangeOffsets := deltas + segX2
+	if len(sub) < rangeOffsets+segX2 {
+		return nil, fmt.Errorf("cmap: truncated format 4 subtable")
+	}
+	runs := tRuns{}
+	for i := 0; i < segX2; i += 2 {
+		end := int(be.Uint16(sub[ends+i:]))
+		start := int(be.Uint16(sub[starts+i:]))
+		delta := int(be.Uint16(sub[deltas+i:]))
+		ro := int(be.Uint16(sub[rangeOffsets+i:]))
+		for c := start; c <= end && c != 0xffff; c++ {
+			glyph := (c + delta) & 0xffff
+			if ro != 0 {
+				addr := rangeOffsets + i + ro + 2*(c-start)
+				if addr+2 > len(sub) {
+					return nil, fmt.Errorf("cmap: glyph id is out of bounds")
+				}
+				glyph = int(be.Uint16(sub[addr:]))
+				if glyph != 0 {
+					glyph = (glyph + delta) & 0xffff
+				}
+			}
+			if glyph != 0 {
+				runs.add(rune(c))
+			}
+		}
+	}
+	return runs, nil
+}
+
+func cmapCoverage6(sub []byte) ([]TRange, error) {
+	be := binary.BigEndian
+	if len(sub) < 10 {
+		return nil, fmt.Errorf("cmap: truncated format 6 subtable")
+	}
+	first := int(be.Uint16(sub[6:]))
+	count := int(be.Uint16(sub[8:]))
+	if len(sub) < 10+count*2 {
+		return nil, fmt.Errorf("cmap: truncated format 6 subtable")
+	}
+	runs := tRuns{}
+	for i := 0; i < count; i++ {
+		if be.Uint16(sub[10+i*2:]) != 0 {
+			runs.add(rune(first + i))
+		}
+	}
+	return runs, nil
+}
+
+// cmapCoverage12 handles both segmented (12) and many-to-one (13) mappings.
+func cmapCoverage12(sub []byte) ([]TRange, error) {
+	be := binary.BigEndian
+	if len(sub) < 16 {
+		return nil, fmt.Errorf("cmap: truncated format 12 subtable")
+	}
+	manyToOne := be.Uint16(sub) == 13
+	n := int(be.Uint32(sub[12:]))
+	if n < 0 || len(sub) < 16+n*12 {
+		return nil, fmt.Errorf("cmap: truncated format 12 subtable")
+	}
+	ret := []TRange{}
+	for i := 0; i < n; i++ {
+		group := sub[16+i*12:]
+		start := be.Uint32(group)
+		end := be.Uint32(group[4:])
+		glyph := be.Uint32(group[8:])
+		if end > unicode.MaxRune {
+			end = unicode.MaxRune
+		}
+		if glyph == 0 {
+			if manyToOne {
+				continue
+			}
+			// only the first code point maps to .notdef
+			start++
+		}
+		if start > end {
+			continue
+		}
+		ret = append(ret, TRange{rune(start), rune(end) + 1})
+	}
+	return ret, nil
+}
+
+// normRanges sorts the ranges and merges the overlapping and adjacent ones.
+func normRanges(ranges []TRange) []TRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Begin < ranges[j].Begin })
+	ret := []TRange{}
+	for _, r := range ranges {
+		if r.Begin >= r.End {
+			continue
+		}
+		if n := len(ret); n > 0 && r.Begin <= ret[n-1].End {
+			if r.End > ret[n-1].End {
+				ret[n-1].End = r.End
+			}
+			continue
+		}
+		ret = append(ret, r)
+	}
+	return ret
+}
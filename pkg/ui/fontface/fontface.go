@@ -1,19 +1,20 @@
 package fontface
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
-	"image/png"
 	"io"
 	"io/ioutil"
 	"math"
-	"os"
+	"runtime"
 	"sort"
-	"unicode"
+	"sync"
 
-	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
 
 	"golang.org/x/image/math/fixed"
 
@@ -27,6 +28,9 @@ type TChar struct {
 	Center  image.Point
 	Offset  image.Point
 	Advance image.Point
+	// Advance26_6 is the exact horizontal advance, it is fractional when
+	// the face has subpixel phases. Advance.X is its integer part.
+	Advance26_6 fixed.Int26_6
 }
 
 // TFontFace -
@@ -34,20 +38,46 @@ type TFontFace struct {
 	// texture    uint32
 	// listbase   uint32
 	// maxW, maxH int
-	Tex     *image.Gray
+	Tex *image.Gray
+	// TexLCD holds the coverage of the color stripes of faces loaded with
+	// WithLCD, Tex is the maximum of them then. Nil for other faces.
+	TexLCD  *image.RGBA
+	LCD     TLCDOrder
 	CharMap map[rune]*TChar
 	Fixed   bool
+	// Origin is the dot relative to the top left corner of a glyph cell
+	Origin image.Point
+	Height int
+	// Phases is the number of horizontal subpixel positions every glyph is
+	// rasterized at, 1 if there is no subpixel positioning.
+	Phases int
+
+	phaseMap map[tPhaseKey]*TChar
+	outlines *sfnt.Font
+	size     float32
+	// source is the font data of the outlines and sourceOffset the offset
+	// of their table directory in it, not zero for collections
+	source       []byte
+	sourceOffset int
+}
+
+type tPhaseKey struct {
+	r     rune
+	phase int
 }
 
 type tMask struct {
 	r         rune
 	destRect  image.Rectangle
+	mask      image.Image
 	maskPoint image.Point
 	advance   fixed.Int26_6
+	phase     int
 }
 
-type tRange struct {
-	begin, end rune
+// TRange - code points from Begin up to but not including End.
+type TRange struct {
+	Begin, End rune
 }
 
 func emptyCol(img *image.Gray, rect image.Rectangle, x int) bool {
@@ -120,55 +150,137 @@ func drawRect(m *image.Gray, x, y, w, h int) {
 	draw.Draw(m, image.Rect(x+1, y+1, x+w-2, y+h-2), &image.Uniform{c}, image.ZP, draw.Src)
 }
 
-func prepData(ttf *truetype.Font, face font.Face) ([]tMask, int, int, fixed.Int26_6, error) {
-	slice := []tMask{}
-	maxAdvance := fixed.Int26_6(-1)
-	volume := -1
-	for r := rune(0); r <= unicode.MaxRune; r++ {
-		if 0xe000 <= r && r <= 0xf8ff ||
-			0xf0000 <= r && r <= 0xffffd ||
-			0x100000 <= r && r <= 0x10fffd {
-			continue
+// IsPrivateUse - reports whether r is in one of the private use areas,
+// fonts map them to glyphs of their own choice.
+func IsPrivateUse(r rune) bool {
+	return 0xe000 <= r && r <= 0xf8ff ||
+		0xf0000 <= r && r <= 0xffffd ||
+		0x100000 <= r && r <= 0x10fffd
+}
+
+// glyphRunes lists the code points of the ranges, private use areas are
+// skipped.
+func glyphRunes(ranges []TRange) []rune {
+	ret := []rune{}
+	for _, rng := range ranges {
+		for r := rng.Begin; r < rng.End; r++ {
+			if IsPrivateUse(r) {
+				continue
+			}
+			ret = append(ret, r)
 		}
-		// if dr, mask, mpt, adv, ok := face.Glyph(fixed.Point26_6{}, r); ok {
-		// 	_ = mask
-		// 	_ = dr
-		// 	_ = mpt
-		// 	_ = adv
-		// 	// fmt.Printf("valid skip %U %v %v %v\n", r, dr, mpt, adv)
-		// 	// continue
-		// }
-		if ttf.Index(r) == 0 {
-			// fmt.Printf("skiped %U\n", r)
-			continue
+	}
+	return ret
+}
+
+// prepData rasterizes the glyphs of runes at every subpixel phase on a pool
+// of workers. Faces are not safe for concurrent use so every worker gets its
+// own one from newFace. The masks are copied since faces reuse their buffers.
+func prepData(runes []rune, phases int, newFace func() (tGlyphRasterizer, error)) ([]tMask, fixed.Int26_6, error) {
+	const chunk = 64
+	total := len(runes) * phases
+	slice := make([]tMask, total)
+	workers := runtime.NumCPU()
+	if n := (total + chunk - 1) / chunk; n < workers {
+		workers = n
+	}
+	jobs := make(chan int)
+	errs := make([]error, workers)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		face, err := newFace()
+		if err != nil {
+			close(jobs)
+			wg.Wait()
+			return nil, 0, err
 		}
-		dr, _, maskp, adv, ok := face.Glyph(fixed.Point26_6{}, r)
-		if !ok {
-			return nil, -1, -1, fixed.Int26_6(0), fmt.Errorf("could not load glyph %q %U", r, r)
+		wg.Add(1)
+		go func(w int, face tGlyphRasterizer) {
+			defer wg.Done()
+			defer face.Close()
+			for begin := range jobs {
+				if errs[w] != nil {
+					continue
+				}
+				end := begin + chunk
+				if end > total {
+					end = total
+				}
+				for i := begin; i < end; i++ {
+					r, phase := runes[i/phases], i%phases
+					dot := fixed.Point26_6{X: fixed.Int26_6(phase * 64 / phases)}
+					dr, mask, maskp, adv, ok := face.Glyph(dot, r)
+					if !ok {
+						errs[w] = fmt.Errorf("could not load glyph %q %U", r, r)
+						break
+					}
+					slice[i] = tMask{
+						r:        r,
+						destRect: dr,
+						mask:     cloneMask(mask, maskp, dr.Size()),
+						advance:  adv,
+						phase:    phase,
+					}
+				}
+			}
+		}(w, face)
+	}
+	for begin := 0; begin < total; begin += chunk {
+		jobs <- begin
+	}
+	close(jobs)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, 0, err
 		}
-		maxAdvance = fixed.Int26_6(misc.MaxInt(int(maxAdvance), int(adv)))
-		volume += dr.Dx() * dr.Dy()
-		maskData := tMask{
-			r:         r,
-			destRect:  dr,
-			maskPoint: maskp,
-			advance:   adv,
+	}
+
+	maxAdvance := fixed.Int26_6(-1)
+	for _, item := range slice {
+		if item.advance > maxAdvance {
+			maxAdvance = item.advance
 		}
-		slice = append(slice, maskData)
+	}
+	return slice, maxAdvance, nil
+}
+
+// atlasSize sorts the masks by height and finds a power of two texture size
+// that fits all of them when they are packed row by row.
+func atlasSize(slice []tMask) (int, int) {
+	volume := -1
+	maxW := 1
+	for _, item := range slice {
+		volume += item.destRect.Dx() * item.destRect.Dy()
+		maxW = misc.MaxInt(maxW, item.destRect.Dx())
 	}
 	sort.SliceStable(slice, func(i, j int) bool { return slice[i].destRect.Dy() > slice[j].destRect.Dy() })
 
 	w := int(math.Ceil(math.Sqrt(float64(volume))))
-	w = maxPow2(w)
-	h := int(math.Ceil(float64(volume) / float64(w)))
-	h = maxPow2(h)
-	fmt.Printf("volume: %v side: %v x %v\n", volume, w, h)
-	fmt.Printf("needed: %v\n", w*h)
-	return slice, w, h, maxAdvance, nil
+	w = maxPow2(misc.MaxInt(w, maxW))
+	posX, posY, maxH := 0, 0, 0
+	for _, item := range slice {
+		if item.destRect.Dx() == 0 || item.destRect.Dy() == 0 {
+			continue
+		}
+		if posX+item.destRect.Dx() > w {
+			posX = 0
+			posY += maxH
+			maxH = 0
+		}
+		posX += item.destRect.Dx()
+		maxH = misc.MaxInt(maxH, item.destRect.Dy())
+	}
+	h := maxPow2(posY + maxH)
+	return w, h
 }
 
 // NewFromReader -
-func NewFromReader(r io.Reader, size int32, lrune, hrune rune) (*TFontFace, error) {
+func NewFromReader(r io.Reader, size int32, lrune, hrune rune, opts ...TOption) (*TFontFace, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
 
 	data, err := ioutil.ReadAll(r)
 	if err != nil {
@@ -179,14 +291,22 @@ func NewFromReader(r io.Reader, size int32, lrune, hrune rune) (*TFontFace, erro
 	if err != nil {
 		return nil, err
 	}
+	outlines, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
 
-	face := truetype.NewFace(ttf, &truetype.Options{
-		Size:       float64(size),
-		Hinting:    font.HintingFull,
-		SubPixelsX: 1,
-		SubPixelsY: 1,
-	})
-	defer face.Close()
+	newFace := func() (tGlyphRasterizer, error) {
+		if o.lcd != LCDNone {
+			return newLCDFace(outlines, size, o), nil
+		}
+		return truetype.NewFace(ttf, &truetype.Options{
+			Size:       float64(size),
+			Hinting:    o.hinting(),
+			SubPixelsX: o.subpixels,
+			SubPixelsY: 1,
+		}), nil
+	}
 
 	fBounds := ttf.Bounds(fixed.Int26_6(size << 6))
 	iBounds := image.Rect(
@@ -196,18 +316,55 @@ func NewFromReader(r io.Reader, size int32, lrune, hrune rune) (*TFontFace, erro
 		-fBounds.Min.Y.Floor(),
 	)
 
-	slice, texW, texH, maxAdvance, err := prepData(ttf, face)
+	ranges, err := coverageAt(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := newFromFace(newFace, ranges, iBounds, o)
 	if err != nil {
 		return nil, err
 	}
+	ret.outlines = outlines
+	ret.size = float32(size)
+	ret.source = data
+
+	return ret, nil
+}
+
+// newFromFace packs every glyph of the ranges into an atlas. newFace is
+// called once per rasterizing worker. iBounds is the union of all glyph
+// bounds in pixels with the Y axis down.
+func newFromFace(newFace func() (tGlyphRasterizer, error), ranges []TRange, iBounds image.Rectangle,
+	o *tOptions) (*TFontFace, error) {
+
+	slice, maxAdvance, err := prepData(glyphRunes(ranges), o.subpixels, newFace)
+	if err != nil {
+		return nil, err
+	}
+	texW, texH := atlasSize(slice)
+	ret, err := pack(slice, texW, texH, maxAdvance, iBounds)
+	if err != nil {
+		return nil, err
+	}
+	ret.LCD = o.lcd
+	return ret, nil
+}
+
+// pack places the masks into a texW x texH atlas row by row. Masks of
+// phase 0 go to CharMap, the other phases are reachable by Char. It fails
+// if the masks do not fit.
+func pack(slice []tMask, texW, texH int, maxAdvance fixed.Int26_6, iBounds image.Rectangle) (*TFontFace, error) {
 
 	charMap := map[rune]*TChar{}
+	phaseMap := map[tPhaseKey]*TChar{}
+	phases := 1
 	posX := 0
 	posY := 0
 	maxH := 0
 	adv := maxAdvance
 	isFixed := true
 	tex := image.NewGray(image.Rect(0, 0, texW, texH))
+	texLCD := (*image.RGBA)(nil)
 	for _, item := range slice {
 		r := item.r
 		tBounds := item.destRect
@@ -216,19 +373,25 @@ func NewFromReader(r io.Reader, size int32, lrune, hrune rune) (*TFontFace, erro
 			isFixed = false
 		}
 
-		if posX+tBounds.Dx() > tex.Bounds().Dx() {
+		if posX+tBounds.Dx() > texW {
 			posX = 0
 			posY += maxH
 			maxH = 0
-			if posY+tBounds.Dy() > tex.Bounds().Dy() {
-				fmt.Println("not enough space in texture")
-			}
+		}
+		if !tBounds.Empty() && (posX+tBounds.Dx() > texW || posY+tBounds.Dy() > texH) {
+			return nil, fmt.Errorf("glyph %q does not fit into the %vx%v atlas", r, texW, texH)
 		}
 
-		if _, ok := charMap[r]; !ok {
-			charMap[r] = &TChar{}
+		char := &TChar{}
+		if item.phase == 0 {
+			if c, ok := charMap[r]; ok {
+				char = c
+			}
+			charMap[r] = char
+		} else {
+			phaseMap[tPhaseKey{r, item.phase}] = char
+			phases = misc.MaxInt(phases, item.phase+1)
 		}
-		char := charMap[r]
 
 		char.Rect.Min.X = posX
 		char.Rect.Min.Y = posY
@@ -237,6 +400,7 @@ func NewFromReader(r io.Reader, size int32, lrune, hrune rune) (*TFontFace, erro
 
 		char.Advance.X = int(item.advance >> 6)
 		char.Advance.Y = iBounds.Dy()
+		char.Advance26_6 = item.advance
 
 		char.Offset.X = tBounds.Min.X - iBounds.Min.X
 		char.Offset.Y = tBounds.Min.Y - iBounds.Min.Y
@@ -247,35 +411,84 @@ func NewFromReader(r io.Reader, size int32, lrune, hrune rune) (*TFontFace, erro
 		if char.Rect.Dx() == 0 || char.Rect.Dy() == 0 {
 			continue
 		}
-		_, mask, _, _, ok := face.Glyph(fixed.Point26_6{}, r)
-		if !ok {
-			return nil, fmt.Errorf("unreachable %q, %U", r, r)
-		}
 		// rect := image.Rect(posX, posY, posX+char.Size.X, posY+char.Size.Y)
 		draw.DrawMask(
 			tex, char.Rect,
 			image.White, image.Point{},
-			mask, item.maskPoint,
+			item.mask, item.maskPoint,
 			draw.Src)
+		if m, ok := item.mask.(*image.RGBA); ok {
+			if texLCD == nil {
+				texLCD = image.NewRGBA(tex.Bounds())
+			}
+			draw.Draw(texLCD, char.Rect, m, item.maskPoint, draw.Src)
+		}
 
 		posX += char.Rect.Dx()
 		maxH = misc.MaxInt(maxH, char.Rect.Dy())
 	}
 
-	f, err := os.Create("img.png")
-	if err != nil {
-		panic(err)
-	}
-	defer f.Close()
-	png.Encode(f, tex)
-
 	return &TFontFace{
-		CharMap: charMap,
-		Tex:     tex,
-		Fixed:   isFixed,
+		CharMap:  charMap,
+		Tex:      tex,
+		TexLCD:   texLCD,
+		Fixed:    isFixed,
+		Origin:   iBounds.Min.Mul(-1),
+		Height:   iBounds.Dy(),
+		Phases:   phases,
+		phaseMap: phaseMap,
 	}, nil
 }
 
-func printBounds(b fixed.Rectangle26_6) {
-	fmt.Printf("Min.X:%d Min.Y:%d Max.X:%d Max.Y:%d\n", b.Min.X, b.Min.Y, b.Max.X, b.Max.Y)
+// Char - returns the glyph rasterized with the dot moved right by
+// phase/Phases of a pixel.
+func (o *TFontFace) Char(r rune, phase int) (*TChar, bool) {
+	if phase == 0 {
+		ch, ok := o.CharMap[r]
+		return ch, ok
+	}
+	ch, ok := o.phaseMap[tPhaseKey{r, phase}]
+	return ch, ok
+}
+
+// NewFromFile - detects the format of the font file by its signature.
+// Collections are loaded starting from the first face, size is ignored by
+// bitmap fonts.
+func NewFromFile(path string, size int32, opts ...TOption) (*TFontFace, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) >= 2 && string(data[:2]) == "\x1f\x8b" {
+		// .pcf.gz and .psf.gz are common in font directories
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		}
+		data, err = ioutil.ReadAll(zr)
+		zr.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		}
+	}
+	if len(data) < 4 {
+		return nil, fmt.Errorf("%v: file is too short", path)
+	}
+
+	r := bytes.NewReader(data)
+	switch {
+	case string(data[:4]) == "\x00\x01\x00\x00" || string(data[:4]) == "true" || string(data[:4]) == "OTTO":
+		return NewOpenTypeFromReader(r, size, opts...)
+	case string(data[:4]) == "ttcf":
+		return NewCollectionFromReader(r, 0, size, opts...)
+	case string(data[:4]) == "wOFF":
+		return NewWOFFFromReader(r, size, opts...)
+	case string(data[:4]) == "\x01fcp":
+		return NewPCFFromReader(r)
+	case string(data[:4]) == "STAR":
+		return NewBDFFromReader(r)
+	case string(data[:2]) == psf1Magic || string(data[:4]) == psf2Magic:
+		return NewPSFFromReader(r)
+	}
+	return nil, fmt.Errorf("%v: unknown font signature %q", path, data[:4])
 }
@@ -0,0 +1,36 @@
+package fontface
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/image/math/fixed"
+)
+
+func TestPack(t *testing.T) {
+	slice := []tMask{}
+	for _, r := range "abc" {
+		slice = append(slice, tMask{
+			r:        r,
+			destRect: image.Rect(0, -4, 4, 0),
+			mask:     image.NewAlpha(image.Rect(0, 0, 4, 4)),
+			advance:  fixed.I(5) + 48,
+		})
+	}
+	bounds := image.Rect(0, -4, 4, 0)
+	if _, err := pack(slice, 8, 4, fixed.I(5), bounds); err == nil {
+		t.Error("packed 3 glyphs of 4x4 into 8x4")
+	}
+	face, err := pack(slice, 8, 8, fixed.I(5), bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := face.CharMap['c']
+	if c == nil || c.Rect != image.Rect(0, 4, 4, 8) {
+		t.Fatalf("'c' at %v, want the start of the second row", c)
+	}
+	// the whole pixels of 5.75, the fraction is kept in Advance26_6
+	if c.Advance.X != 5 || c.Advance26_6 != fixed.I(5)+48 {
+		t.Errorf("advance %v, %v, want 5, 5:48", c.Advance.X, c.Advance26_6)
+	}
+}
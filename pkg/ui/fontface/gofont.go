@@ -0,0 +1,120 @@
+package fontface
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomedium"
+	"golang.org/x/image/font/gofont/gomediumitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/gomonobolditalic"
+	"golang.org/x/image/font/gofont/gomonoitalic"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/gofont/gosmallcaps"
+	"golang.org/x/image/font/gofont/gosmallcapsitalic"
+)
+
+// TGoStyle - a variant of the Go font family.
+type TGoStyle int
+
+// Go font variants
+const (
+	GoRegular = TGoStyle(iota)
+	GoBold
+	GoItalic
+	GoBoldItalic
+	GoMedium
+	GoMediumItalic
+	GoMono
+	GoMonoBold
+	GoMonoItalic
+	GoMonoBoldItalic
+	GoSmallcaps
+	GoSmallcapsItalic
+
+	goStyleLen
+)
+
+var goFonts = [goStyleLen]struct {
+	name string
+	ttf  []byte
+}{
+	GoRegular:         {"regular", goregular.TTF},
+	GoBold:            {"bold", gobold.TTF},
+	GoItalic:          {"italic", goitalic.TTF},
+	GoBoldItalic:      {"bolditalic", gobolditalic.TTF},
+	GoMedium:          {"medium", gomedium.TTF},
+	GoMediumItalic:    {"mediumitalic", gomediumitalic.TTF},
+	GoMono:            {"mono", gomono.TTF},
+	GoMonoBold:        {"monobold", gomonobold.TTF},
+	GoMonoItalic:      {"monoitalic", gomonoitalic.TTF},
+	GoMonoBoldItalic:  {"monobolditalic", gomonobolditalic.TTF},
+	GoSmallcaps:       {"smallcaps", gosmallcaps.TTF},
+	GoSmallcapsItalic: {"smallcapsitalic", gosmallcapsitalic.TTF},
+}
+
+type tGoKey struct {
+	style TGoStyle
+	size  int32
+}
+
+var (
+	goMu    sync.Mutex
+	goFaces = map[tGoKey]*TFontFace{}
+)
+
+// String -
+func (o TGoStyle) String() string {
+	if o < 0 || o >= goStyleLen {
+		return fmt.Sprintf("TGoStyle(%d)", int(o))
+	}
+	return goFonts[o].name
+}
+
+// ParseGoStyle - accepts the names returned by TGoStyle.String, case and
+// spaces are ignored ("Mono Bold").
+func ParseGoStyle(s string) (TGoStyle, error) {
+	name := strings.ToLower(strings.Join(strings.Fields(s), ""))
+	for i, f := range goFonts {
+		if f.name == name {
+			return TGoStyle(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown Go font style %q", s)
+}
+
+// GoTTF - returns the TrueType data of the variant.
+func GoTTF(style TGoStyle) ([]byte, error) {
+	if style < 0 || style >= goStyleLen {
+		return nil, fmt.Errorf("unknown Go font style %v", style)
+	}
+	return goFonts[style].ttf, nil
+}
+
+// Go - returns a face of the built-in Go font. Faces are built once per
+// style and size and shared, do not modify them.
+func Go(style TGoStyle, size int32) (*TFontFace, error) {
+	ttf, err := GoTTF(style)
+	if err != nil {
+		return nil, err
+	}
+	key := tGoKey{style, size}
+
+	goMu.Lock()
+	defer goMu.Unlock()
+	if face, ok := goFaces[key]; ok {
+		return face, nil
+	}
+	face, err := NewOpenTypeFromReader(bytes.NewReader(ttf), size)
+	if err != nil {
+		return nil, fmt.Errorf("go %v: %v", style, err)
+	}
+	goFaces[key] = face
+	return face, nil
+}
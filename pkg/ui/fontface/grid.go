@@ -0,0 +1,177 @@
+package fontface
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png" // sprite sheets are mostly png files
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// TGridOptions - describes a sprite sheet of glyphs laid out in a fixed grid.
+type TGridOptions struct {
+	CellW, CellH int
+	// FirstRune is the rune of the top left cell, the next cells follow in
+	// the row by row order. Used when both Runes and Map are empty.
+	FirstRune rune
+	// Runes lists the runes of the cells in the row by row order.
+	Runes string
+	// Map assigns runes to cell indices directly, see ParseGridMap.
+	Map map[int]rune
+	// Baseline is the distance from the top of a cell to the baseline.
+	// Zero means the bottom of the cell.
+	Baseline int
+	// Trim sets the advance of a glyph to the width of its ink plus Spacing.
+	Trim    bool
+	Spacing int
+	// Invert treats dark pixels as ink on opaque sheets.
+	Invert bool
+}
+
+// NewGridFromReader - loads a grid font from an image file (png by default).
+func NewGridFromReader(r io.Reader, opts TGridOptions) (*TFontFace, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return NewGridFromImage(img, opts)
+}
+
+// NewGridFromImage - cuts the image into cells and packs them into an atlas.
+// Transparent sheets use alpha as coverage, opaque ones use luminance.
+func NewGridFromImage(img image.Image, opts TGridOptions) (*TFontFace, error) {
+	if opts.CellW <= 0 || opts.CellH <= 0 {
+		return nil, fmt.Errorf("bad cell size %vx%v", opts.CellW, opts.CellH)
+	}
+	b := img.Bounds()
+	cols := b.Dx() / opts.CellW
+	rows := b.Dy() / opts.CellH
+	if cols == 0 || rows == 0 {
+		return nil, fmt.Errorf("image %vx%v is smaller than a cell %vx%v", b.Dx(), b.Dy(), opts.CellW, opts.CellH)
+	}
+	baseline := opts.Baseline
+	if baseline == 0 {
+		baseline = opts.CellH
+	}
+
+	cellRune := opts.Map
+	if cellRune == nil {
+		cellRune = map[int]rune{}
+		if opts.Runes != "" {
+			i := 0
+			for _, r := range opts.Runes {
+				cellRune[i] = r
+				i++
+			}
+		} else {
+			for i := 0; i < cols*rows; i++ {
+				cellRune[i] = opts.FirstRune + rune(i)
+			}
+		}
+	}
+
+	coverage := gridCoverage(img, opts.Invert)
+	glyphs := []tBitmap{}
+	for i := 0; i < cols*rows; i++ {
+		r, ok := cellRune[i]
+		if !ok {
+			continue
+		}
+		cell := image.Rect(0, 0, opts.CellW, opts.CellH).Add(
+			b.Min.Add(image.Pt(i%cols*opts.CellW, i/cols*opts.CellH)))
+		gray := image.NewGray(cell)
+		for y := cell.Min.Y; y < cell.Max.Y; y++ {
+			for x := cell.Min.X; x < cell.Max.X; x++ {
+				gray.SetGray(x, y, coverage(x, y))
+			}
+		}
+
+		ink := tightBounds(gray)
+		mask := image.NewAlpha(image.Rect(0, 0, ink.Dx(), ink.Dy()))
+		for y := 0; y < ink.Dy(); y++ {
+			for x := 0; x < ink.Dx(); x++ {
+				mask.Pix[y*mask.Stride+x] = gray.GrayAt(ink.Min.X+x, ink.Min.Y+y).Y
+			}
+		}
+
+		origin := ink.Min.Sub(cell.Min).Sub(image.Pt(0, baseline))
+		advance := opts.CellW
+		if opts.Trim && !ink.Empty() {
+			origin.X = 0
+			advance = ink.Dx() + opts.Spacing
+		}
+		glyphs = append(glyphs, tBitmap{
+			r:       r,
+			mask:    mask,
+			origin:  origin,
+			advance: advance,
+		})
+	}
+	return newFromBitmaps(glyphs, image.Rect(0, -baseline, opts.CellW, opts.CellH-baseline))
+}
+
+func gridCoverage(img image.Image, invert bool) func(x, y int) color.Gray {
+	opaque := true
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		opaque = o.Opaque()
+	}
+	if !opaque {
+		return func(x, y int) color.Gray {
+			_, _, _, a := img.At(x, y).RGBA()
+			return color.Gray{uint8(a >> 8)}
+		}
+	}
+	return func(x, y int) color.Gray {
+		c := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+		if invert {
+			c.Y = 0xff - c.Y
+		}
+		return c
+	}
+}
+
+// ParseGridMap - reads a mapping file for TGridOptions.Map. Every line holds
+// a cell index and a rune, either as a literal or as U+XXXX. Empty lines and
+// lines starting with '#' are skipped.
+func ParseGridMap(r io.Reader) (map[int]rune, error) {
+	ret := map[int]rune{}
+	sc := bufio.NewScanner(r)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("grid map: line %v: want <index> <rune> but got %q", line, text)
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("grid map: line %v: bad cell index %q", line, fields[0])
+		}
+		var ch rune
+		switch spec := fields[1]; {
+		case utf8.RuneCountInString(spec) == 1:
+			ch, _ = utf8.DecodeRuneInString(spec)
+		case strings.HasPrefix(spec, "U+") || strings.HasPrefix(spec, "u+"):
+			v, err := strconv.ParseUint(spec[2:], 16, 32)
+			if err != nil || v > utf8.MaxRune {
+				return nil, fmt.Errorf("grid map: line %v: bad rune %q", line, spec)
+			}
+			ch = rune(v)
+		default:
+			return nil, fmt.Errorf("grid map: line %v: bad rune %q", line, spec)
+		}
+		ret[index] = ch
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,116 @@
+package fontface
+
+import (
+	"image/color"
+	"unicode"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// TSpan - a run of text drawn with one color. Nil color means the current
+// color of the renderer.
+type TSpan struct {
+	Text  string
+	Color color.Color
+}
+
+// TGlyph - a laid out glyph. X, Y is the top left corner of its cell in the
+// line, the dot is at X, Y + TFontFace.Origin. Char is rasterized at the
+// subpixel Phase, so the dot is Phase/TFontFace.Phases of a pixel further
+// right.
+type TGlyph struct {
+	Rune  rune
+	Char  *TChar
+	X, Y  int
+	Phase int
+	Color color.Color
+}
+
+// Layout - places the spans line by line. Lines are broken at '\n' and, when
+// maxWidth is positive, before a word that does not fit. Trailing spaces may
+// hang over maxWidth. Runes missing in the face are skipped.
+func (o *TFontFace) Layout(spans []TSpan, maxWidth int) []TGlyph {
+	return o.LayoutAt(spans, 0, maxWidth)
+}
+
+// LayoutAt - same as Layout but the lines start at x, which may be
+// fractional for faces with subpixel phases. X of the glyphs includes x.
+func (o *TFontFace) LayoutAt(spans []TSpan, x0 float32, maxWidth int) []TGlyph {
+	ret := []TGlyph{}
+	word := []TGlyph{}
+	start := fixed.Int26_6(x0 * 64)
+	limit := start + fixed.I(maxWidth)
+	// X of the glyphs is kept in 26.6 until they are placed
+	wordW := fixed.Int26_6(0)
+	x, y := start, 0
+
+	newLine := func() {
+		x = start
+		y += o.Height
+	}
+	flush := func() {
+		if maxWidth > 0 && x > start && x+wordW > limit {
+			newLine()
+		}
+		for _, g := range word {
+			ret = append(ret, o.place(g, x+fixed.Int26_6(g.X), y))
+		}
+		x += wordW
+		word = word[:0]
+		wordW = 0
+	}
+
+	for _, span := range spans {
+		for _, r := range span.Text {
+			if r == '\n' {
+				flush()
+				newLine()
+				continue
+			}
+			ch, ok := o.CharMap[r]
+			if !ok {
+				continue
+			}
+			if unicode.IsSpace(r) {
+				flush()
+				ret = append(ret, o.place(TGlyph{Rune: r, Char: ch, Color: span.Color}, x, y))
+				x += ch.Advance26_6
+				continue
+			}
+			word = append(word, TGlyph{Rune: r, Char: ch, X: int(wordW), Color: span.Color})
+			wordW += ch.Advance26_6
+		}
+	}
+	flush()
+	return ret
+}
+
+// place moves the glyph to the pixel and the nearest subpixel phase of x.
+func (o *TFontFace) place(g TGlyph, x fixed.Int26_6, y int) TGlyph {
+	g.X, g.Y = x.Floor(), y
+	if o.Phases <= 1 {
+		return g
+	}
+	phase := (int(x&63)*o.Phases + 32) >> 6
+	if phase == o.Phases {
+		phase = 0
+		g.X++
+	}
+	if ch, ok := o.Char(g.Rune, phase); ok {
+		g.Char, g.Phase = ch, phase
+	}
+	return g
+}
+
+// LayoutBounds - returns the size of the laid out glyphs.
+func (o *TFontFace) LayoutBounds(glyphs []TGlyph) (w, h int) {
+	for _, g := range glyphs {
+		if x := g.X + g.Char.Advance.X; x > w {
+			w = x
+		}
+		if y := g.Y + o.Height; y > h {
+			h = y
+		}
+	}
+	return w, h
+}
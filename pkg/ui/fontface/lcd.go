@@ -0,0 +1,155 @@
+package fontface
+
+import (
+	"image"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// TLCDOrder - order of the color stripes of LCD pixels from left to right.
+type TLCDOrder int
+
+// LCD orders
+const (
+	LCDNone = TLCDOrder(iota)
+	LCDRGB
+	LCDBGR
+)
+
+// lcdFilter is the default FreeType LCD filter, it spreads every subpixel
+// over its neighbours to tame the color fringes. The weights sum to 256.
+var lcdFilter = [5]int{8, 77, 86, 77, 8}
+
+// tGlyphRasterizer - the part of font.Face used to build atlases.
+type tGlyphRasterizer interface {
+	Glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool)
+	Close() error
+}
+
+// tLCDFace rasterizes outlines at three times the horizontal resolution and
+// filters them into *image.RGBA masks holding the coverage of every stripe.
+// Alpha is the maximum of the stripes.
+type tLCDFace struct {
+	f       *sfnt.Font
+	buf     sfnt.Buffer
+	ppem    fixed.Int26_6
+	hinting font.Hinting
+	order   TLCDOrder
+	z       *vector.Rasterizer
+}
+
+func newLCDFace(f *sfnt.Font, size int32, o *tOptions) *tLCDFace {
+	return &tLCDFace{
+		f:       f,
+		ppem:    fixed.I(int(size)),
+		hinting: o.hinting(),
+		order:   o.lcd,
+		z:       vector.NewRasterizer(0, 0),
+	}
+}
+
+// Close -
+func (o *tLCDFace) Close() error {
+	return nil
+}
+
+// Glyph -
+func (o *tLCDFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	index, err := o.f.GlyphIndex(&o.buf, r)
+	if err != nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	adv, err := o.f.GlyphAdvance(&o.buf, index, o.ppem, o.hinting)
+	if err != nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	segs, err := o.f.LoadGlyph(&o.buf, index, o.ppem, nil)
+	if err != nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	if len(segs) == 0 {
+		return image.Rectangle{}, image.NewRGBA(image.Rectangle{}), image.Point{}, adv, true
+	}
+
+	// the filter spreads coverage by two stripes, less than a pixel
+	b := segs.Bounds()
+	dr := image.Rect(
+		(b.Min.X+dot.X).Floor()-1,
+		(b.Min.Y + dot.Y).Floor(),
+		(b.Max.X+dot.X).Ceil()+1,
+		(b.Max.Y + dot.Y).Ceil(),
+	)
+	w, h := dr.Dx(), dr.Dy()
+	ox := float32(dot.X-fixed.I(dr.Min.X)) / 64
+	oy := float32(dot.Y-fixed.I(dr.Min.Y)) / 64
+	pt := func(p fixed.Point26_6) (float32, float32) {
+		return (float32(p.X)/64 + ox) * 3, float32(p.Y)/64 + oy
+	}
+	o.z.Reset(w*3, h)
+	for _, seg := range segs {
+		x0, y0 := pt(seg.Args[0])
+		x1, y1 := pt(seg.Args[1])
+		x2, y2 := pt(seg.Args[2])
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			o.z.MoveTo(x0, y0)
+		case sfnt.SegmentOpLineTo:
+			o.z.LineTo(x0, y0)
+		case sfnt.SegmentOpQuadTo:
+			o.z.QuadTo(x0, y0, x1, y1)
+		case sfnt.SegmentOpCubeTo:
+			o.z.CubeTo(x0, y0, x1, y1, x2, y2)
+		}
+	}
+	o.z.ClosePath()
+	stripes := image.NewAlpha(image.Rect(0, 0, w*3, h))
+	o.z.Draw(stripes, stripes.Bounds(), image.Opaque, image.Point{})
+
+	mask := image.NewRGBA(image.Rect(0, 0, w, h))
+	sub := make([]int, w*3)
+	for y := 0; y < h; y++ {
+		row := stripes.Pix[y*stripes.Stride : y*stripes.Stride+w*3]
+		for i := range sub {
+			sum := 0
+			for k, weight := range lcdFilter {
+				if j := i + k - len(lcdFilter)/2; j >= 0 && j < len(row) {
+					sum += int(row[j]) * weight
+				}
+			}
+			sub[i] = sum >> 8
+		}
+		for x := 0; x < w; x++ {
+			c0, c1, c2 := uint8(sub[x*3]), uint8(sub[x*3+1]), uint8(sub[x*3+2])
+			if o.order == LCDBGR {
+				c0, c2 = c2, c0
+			}
+			a := c0
+			if c1 > a {
+				a = c1
+			}
+			if c2 > a {
+				a = c2
+			}
+			p := mask.PixOffset(x, y)
+			mask.Pix[p+0], mask.Pix[p+1], mask.Pix[p+2], mask.Pix[p+3] = c0, c1, c2, a
+		}
+	}
+	return dr, mask, image.Point{}, adv, true
+}
+
+// cloneMask copies the part of the mask a face may reuse on the next call.
+// LCD masks keep their colors, others become alpha.
+func cloneMask(mask image.Image, maskp image.Point, size image.Point) image.Image {
+	var dst draw.Image
+	if _, ok := mask.(*image.RGBA); ok {
+		dst = image.NewRGBA(image.Rectangle{Max: size})
+	} else {
+		dst = image.NewAlpha(image.Rectangle{Max: size})
+	}
+	draw.Draw(dst, dst.Bounds(), mask, maskp, draw.Src)
+	return dst
+}
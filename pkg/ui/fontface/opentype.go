@@ -0,0 +1,114 @@
+package fontface
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// NewOpenTypeFromReader - loads a TrueType or CFF flavored OpenType font.
+func NewOpenTypeFromReader(r io.Reader, size int32, opts ...TOption) (*TFontFace, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return newFromSFNT(f, data, 0, size, opts)
+}
+
+// NewCollectionFromReader - loads the face number index of a .ttc/.otc collection.
+func NewCollectionFromReader(r io.Reader, index int, size int32, opts ...TOption) (*TFontFace, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := sfnt.ParseCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= col.NumFonts() {
+		return nil, fmt.Errorf("face index %v is out of range 0..%v", index, col.NumFonts()-1)
+	}
+	f, err := col.Font(index)
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := sfntOffsets(data)
+	if err != nil {
+		return nil, err
+	}
+	return newFromSFNT(f, data, offsets[index], size, opts)
+}
+
+// NewWOFFFromReader - loads a WOFF 1.0 font.
+func NewWOFFFromReader(r io.Reader, size int32, opts ...TOption) (*TFontFace, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = decodeWOFF(data)
+	if err != nil {
+		return nil, err
+	}
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return newFromSFNT(f, data, 0, size, opts)
+}
+
+// newFromSFNT builds the atlas of f, the font data and the offset of its
+// table directory are needed to read the cmap.
+func newFromSFNT(f *sfnt.Font, data []byte, offset int, size int32, opts []TOption) (*TFontFace, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	newFace := func() (tGlyphRasterizer, error) {
+		if o.lcd != LCDNone {
+			return newLCDFace(f, size, o), nil
+		}
+		return opentype.NewFace(f, &opentype.FaceOptions{
+			Size:    float64(size),
+			DPI:     72,
+			Hinting: o.hinting(),
+		})
+	}
+
+	buf := &sfnt.Buffer{}
+	fBounds, err := f.Bounds(buf, fixed.I(int(size)), o.hinting())
+	if err != nil {
+		return nil, err
+	}
+	iBounds := image.Rect(
+		fBounds.Min.X.Floor(),
+		fBounds.Min.Y.Floor(),
+		fBounds.Max.X.Ceil(),
+		fBounds.Max.Y.Ceil(),
+	)
+
+	ranges, err := coverageAt(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := newFromFace(newFace, ranges, iBounds, o)
+	if err != nil {
+		return nil, err
+	}
+	ret.outlines = f
+	ret.size = float32(size)
+	ret.source = data
+	ret.sourceOffset = offset
+	return ret, nil
+}
@@ -0,0 +1,69 @@
+package fontface
+
+import (
+	"fmt"
+
+	"golang.org/x/image/font"
+)
+
+// maxSubpixels is the finest subpixel grid truetype faces support.
+const maxSubpixels = 64
+
+type (
+	// TOption - an option of the vector font loaders, bitmap fonts ignore
+	// them.
+	TOption func(o *tOptions) error
+
+	tOptions struct {
+		subpixels int
+		lcd       TLCDOrder
+	}
+)
+
+// WithSubpixels - rasterizes n horizontal subpixel phases of every glyph,
+// see TFontFace.Char. Advances are not rounded to whole pixels then, so the
+// text is laid out with fractional positions. The atlas is n times bigger.
+// n must be a power of two, truetype faces snap the dot to such a grid.
+func WithSubpixels(n int) TOption {
+	return func(o *tOptions) error {
+		if n < 1 || n > maxSubpixels {
+			return fmt.Errorf("subpixels %v is out of range 1..%v", n, maxSubpixels)
+		}
+		if n&(n-1) != 0 {
+			return fmt.Errorf("subpixels %v is not a power of two", n)
+		}
+		o.subpixels = n
+		return nil
+	}
+}
+
+// WithLCD - rasterizes the glyphs for LCD screens with the stripe order,
+// see TFontFace.TexLCD. LCDNone keeps grayscale antialiasing.
+func WithLCD(order TLCDOrder) TOption {
+	return func(o *tOptions) error {
+		if order < LCDNone || order > LCDBGR {
+			return fmt.Errorf("unknown LCD order %v", order)
+		}
+		o.lcd = order
+		return nil
+	}
+}
+
+func newOptions(opts []TOption) (*tOptions, error) {
+	ret := &tOptions{subpixels: 1}
+	for _, opt := range opts {
+		if err := opt(ret); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// hinting rounds the metrics to whole pixels unless glyphs are positioned
+// with subpixel precision.
+func (o *tOptions) hinting() font.Hinting {
+	if o.subpixels > 1 {
+		return font.HintingNone
+	}
+	return font.HintingFull
+}
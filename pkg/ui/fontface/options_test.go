@@ -0,0 +1,83 @@
+package fontface
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestWithSubpixelsRange(t *testing.T) {
+	for _, n := range []int{1, 2, 4, 8, 64} {
+		if _, err := newOptions([]TOption{WithSubpixels(n)}); err != nil {
+			t.Errorf("WithSubpixels(%v): %v", n, err)
+		}
+	}
+	for _, n := range []int{0, 3, 5, 6, 12, 128} {
+		if _, err := newOptions([]TOption{WithSubpixels(n)}); err == nil {
+			t.Errorf("WithSubpixels(%v) is accepted", n)
+		}
+	}
+}
+
+// TestSubpixelPhaseShift compares every phase k of n with the glyph
+// rasterized at exactly k/n px by a face that does not quantize the dot.
+func TestSubpixelPhaseShift(t *testing.T) {
+	data, err := GoTTF(GoRegular)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ttf, err := truetype.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const size = 16
+	ref := truetype.NewFace(ttf, &truetype.Options{Size: size, Hinting: font.HintingNone, SubPixelsX: 64})
+	defer ref.Close()
+
+	for _, n := range []int{2, 4, 8} {
+		face, err := NewFromReader(bytes.NewReader(data), size, 'A', 'z'+1, WithSubpixels(n))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if face.Phases != n {
+			t.Fatalf("n=%v: %v phases", n, face.Phases)
+		}
+		for _, r := range "HIlmo" {
+			for k := 0; k < n; k++ {
+				ch, ok := face.Char(r, k)
+				if !ok {
+					t.Fatalf("n=%v: no phase %v of %q", n, k, r)
+				}
+				dr, mask, maskp, _, _ := ref.Glyph(fixed.Point26_6{X: fixed.Int26_6(k * 64 / n)}, r)
+				if got := ch.Rect.Sub(ch.Rect.Min).Add(ch.Center.Mul(-1)); got != dr {
+					t.Errorf("n=%v: phase %v of %q covers %v, want %v", n, k, r, got, dr)
+					continue
+				}
+				if d := maxMaskDiff(face.Tex, ch.Rect.Min, mask, maskp, dr.Size()); d > 1 {
+					t.Errorf("n=%v: phase %v of %q differs by %v from the glyph at %v/%v px", n, k, r, d, k, n)
+				}
+			}
+		}
+	}
+}
+
+func maxMaskDiff(tex *image.Gray, at image.Point, mask image.Image, maskp image.Point, size image.Point) int {
+	ret := 0
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			_, _, _, a := mask.At(maskp.X+x, maskp.Y+y).RGBA()
+			d := int(tex.GrayAt(at.X+x, at.Y+y).Y) - int(a>>8)
+			if d < 0 {
+				d = -d
+			}
+			if d > ret {
+				ret = d
+			}
+		}
+	}
+	return ret
+}
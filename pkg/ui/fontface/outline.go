@@ -0,0 +1,107 @@
+package fontface
+
+import (
+	"fmt"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// TSegmentOp -
+type TSegmentOp int
+
+// segment operators
+const (
+	MoveTo = TSegmentOp(iota)
+	LineTo
+	QuadTo
+	CubeTo
+)
+
+// TPoint -
+type TPoint struct {
+	X, Y float32
+}
+
+// TSegment - Args holds 1 point for MoveTo and LineTo, 2 for QuadTo and 3
+// for CubeTo. The Y axis increases down, the dot is at the origin.
+type TSegment struct {
+	Op   TSegmentOp
+	Args [3]TPoint
+}
+
+// TOutline - contours of a glyph, every contour starts with MoveTo.
+type TOutline []TSegment
+
+// HasOutlines - reports if the face was loaded from a vector font.
+func (o *TFontFace) HasOutlines() bool {
+	return o != nil && o.outlines != nil
+}
+
+// Outline - returns the contours of a glyph scaled to size pixels per em.
+func (o *TFontFace) Outline(r rune, size float32) (TOutline, error) {
+	index, err := o.glyphIndex(r)
+	if err != nil {
+		return nil, err
+	}
+	buf := &sfnt.Buffer{}
+	upem := o.outlines.UnitsPerEm()
+	// loading at ppem == unitsPerEm keeps the coordinates in font units
+	segs, err := o.outlines.LoadGlyph(buf, index, fixed.I(int(upem)), nil)
+	if err != nil {
+		return nil, err
+	}
+	k := size / float32(upem) / 64
+	ret := make(TOutline, len(segs))
+	for i, seg := range segs {
+		ret[i].Op = TSegmentOp(seg.Op)
+		for j, p := range seg.Args {
+			ret[i].Args[j] = TPoint{float32(p.X) * k, float32(p.Y) * k}
+		}
+	}
+	return ret, nil
+}
+
+// GlyphAdvance - returns the unhinted advance of a glyph at size pixels per em.
+func (o *TFontFace) GlyphAdvance(r rune, size float32) (float32, error) {
+	index, err := o.glyphIndex(r)
+	if err != nil {
+		return 0, err
+	}
+	upem := o.outlines.UnitsPerEm()
+	adv, err := o.outlines.GlyphAdvance(&sfnt.Buffer{}, index, fixed.I(int(upem)), font.HintingNone)
+	if err != nil {
+		return 0, err
+	}
+	return float32(adv) * size / float32(upem) / 64, nil
+}
+
+// Kern - returns the unhinted kerning between two glyphs at size pixels per em.
+func (o *TFontFace) Kern(r0, r1 rune, size float32) float32 {
+	i0, err0 := o.glyphIndex(r0)
+	i1, err1 := o.glyphIndex(r1)
+	if err0 != nil || err1 != nil {
+		return 0
+	}
+	upem := o.outlines.UnitsPerEm()
+	k, err := o.outlines.Kern(&sfnt.Buffer{}, i0, i1, fixed.I(int(upem)), font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return float32(k) * size / float32(upem) / 64
+}
+
+func (o *TFontFace) glyphIndex(r rune) (sfnt.GlyphIndex, error) {
+	if !o.HasOutlines() {
+		return 0, fmt.Errorf("font face has no outlines")
+	}
+	index, err := o.outlines.GlyphIndex(&sfnt.Buffer{}, r)
+	if err != nil {
+		return 0, err
+	}
+	if index == 0 {
+		return 0, fmt.Errorf("glyph %q %U is not found", r, r)
+	}
+	return index, nil
+}
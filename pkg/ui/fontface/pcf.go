@@ -0,0 +1,244 @@
+package fontface
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+)
+
+// pcf table types
+const (
+	pcfMetrics      = 1 << 2
+	pcfBitmaps      = 1 << 3
+	pcfBDFEncodings = 1 << 5
+)
+
+// pcf format bits
+const (
+	pcfFormatMask        = 0xffffff00
+	pcfDefaultFormat     = 0x00000000
+	pcfCompressedMetrics = 0x00000100
+	pcfByteOrderMSB      = 1 << 2
+	pcfBitOrderMSB       = 1 << 3
+	pcfGlyphPadMask      = 3
+	pcfScanUnitMask      = 3 << 4
+)
+
+const (
+	pcfNoEncodedGlyph       = 0xffff
+	pcfTOCEntrySize         = 16
+	pcfMetricsSize          = 12
+	pcfCompressedMetricSize = 5
+)
+
+type tPCFTable struct {
+	format uint32
+	size   uint32
+	offset uint32
+}
+
+type tPCFMetric struct {
+	lsb, rsb, width, ascent, descent int
+}
+
+// NewPCFFromReader - loads an X11 Portable Compiled Format (.pcf) font.
+// Encodings are treated as unicode code points.
+func NewPCFFromReader(r io.Reader) (*TFontFace, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	glyphs, err := parsePCF(data)
+	if err != nil {
+		return nil, err
+	}
+	return newFromBitmaps(glyphs, image.Rectangle{})
+}
+
+func parsePCF(data []byte) ([]tBitmap, error) {
+	if len(data) < 8 || string(data[:4]) != "\x01fcp" {
+		return nil, fmt.Errorf("pcf: bad signature")
+	}
+	le := binary.LittleEndian
+	count := int(le.Uint32(data[4:]))
+	if len(data) < 8+count*pcfTOCEntrySize {
+		return nil, fmt.Errorf("pcf: truncated table of contents")
+	}
+	tables := map[uint32]tPCFTable{}
+	for i := 0; i < count; i++ {
+		p := data[8+i*pcfTOCEntrySize:]
+		tables[le.Uint32(p[0:])] = tPCFTable{
+			format: le.Uint32(p[4:]),
+			size:   le.Uint32(p[8:]),
+			offset: le.Uint32(p[12:]),
+		}
+	}
+	table := func(typ uint32, name string) ([]byte, uint32, binary.ByteOrder, error) {
+		t, ok := tables[typ]
+		if !ok {
+			return nil, 0, nil, fmt.Errorf("pcf: %v table is missing", name)
+		}
+		end := uint64(t.offset) + uint64(t.size)
+		if t.size < 4 || end > uint64(len(data)) {
+			return nil, 0, nil, fmt.Errorf("pcf: %v table is out of bounds", name)
+		}
+		b := data[t.offset:end]
+		// the format inside a table is always little endian
+		format := le.Uint32(b)
+		var order binary.ByteOrder = binary.LittleEndian
+		if format&pcfByteOrderMSB != 0 {
+			order = binary.BigEndian
+		}
+		return b[4:], format, order, nil
+	}
+
+	metrics, err := parsePCFMetrics(table(pcfMetrics, "metrics"))
+	if err != nil {
+		return nil, err
+	}
+
+	b, format, order, err := table(pcfBitmaps, "bitmaps")
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 4 {
+		return nil, fmt.Errorf("pcf: truncated bitmaps table")
+	}
+	numGlyphs := int(order.Uint32(b))
+	if numGlyphs != len(metrics) {
+		return nil, fmt.Errorf("pcf: %v bitmaps but %v metrics", numGlyphs, len(metrics))
+	}
+	if len(b) < 4+numGlyphs*4+16 {
+		return nil, fmt.Errorf("pcf: truncated bitmaps table")
+	}
+	offsets := make([]int, numGlyphs)
+	for i := range offsets {
+		offsets[i] = int(order.Uint32(b[4+i*4:]))
+	}
+	pad := 1 << (format & pcfGlyphPadMask)
+	sizes := b[4+numGlyphs*4:]
+	bitmapSize := int(order.Uint32(sizes[(format&pcfGlyphPadMask)*4:]))
+	bits := b[4+numGlyphs*4+16:]
+	if len(bits) < bitmapSize {
+		return nil, fmt.Errorf("pcf: truncated bitmap data")
+	}
+	bits = append([]byte(nil), bits[:bitmapSize]...)
+	if format&pcfBitOrderMSB == 0 {
+		for i := range bits {
+			bits[i] = reverseBits(bits[i])
+		}
+	}
+	if (format&pcfByteOrderMSB != 0) != (format&pcfBitOrderMSB != 0) {
+		unit := 1 << ((format & pcfScanUnitMask) >> 4)
+		for i := 0; i+unit <= len(bits); i += unit {
+			for j, k := i, i+unit-1; j < k; j, k = j+1, k-1 {
+				bits[j], bits[k] = bits[k], bits[j]
+			}
+		}
+	}
+
+	b, _, order, err = table(pcfBDFEncodings, "encodings")
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 10 {
+		return nil, fmt.Errorf("pcf: truncated encodings table")
+	}
+	minByte2 := int(order.Uint16(b[0:]))
+	maxByte2 := int(order.Uint16(b[2:]))
+	minByte1 := int(order.Uint16(b[4:]))
+	maxByte1 := int(order.Uint16(b[6:]))
+	cols := maxByte2 - minByte2 + 1
+	rows := maxByte1 - minByte1 + 1
+	if cols <= 0 || rows <= 0 || len(b) < 10+cols*rows*2 {
+		return nil, fmt.Errorf("pcf: bad encodings table")
+	}
+
+	ret := []tBitmap{}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			index := int(order.Uint16(b[10+(row*cols+col)*2:]))
+			if index == pcfNoEncodedGlyph {
+				continue
+			}
+			if index >= numGlyphs {
+				return nil, fmt.Errorf("pcf: glyph index %v is out of range", index)
+			}
+			m := metrics[index]
+			w := m.rsb - m.lsb
+			h := m.ascent + m.descent
+			stride := ((w+7)/8 + pad - 1) / pad * pad
+			if offsets[index] > len(bits) {
+				return nil, fmt.Errorf("pcf: glyph %v is out of bounds", index)
+			}
+			mask, err := bitsToAlpha(bits[offsets[index]:], w, h, stride)
+			if err != nil {
+				return nil, fmt.Errorf("pcf: glyph %v: %v", index, err)
+			}
+			ret = append(ret, tBitmap{
+				r:       rune((row+minByte1)<<8 | (col + minByte2)),
+				mask:    mask,
+				origin:  image.Pt(m.lsb, -m.ascent),
+				advance: m.width,
+			})
+		}
+	}
+	return ret, nil
+}
+
+func parsePCFMetrics(b []byte, format uint32, order binary.ByteOrder, err error) ([]tPCFMetric, error) {
+	if err != nil {
+		return nil, err
+	}
+	ret := []tPCFMetric{}
+	switch format & pcfFormatMask {
+	default:
+		return nil, fmt.Errorf("pcf: unsupported metrics format %#x", format)
+	case pcfCompressedMetrics:
+		if len(b) < 2 {
+			return nil, fmt.Errorf("pcf: truncated metrics table")
+		}
+		count := int(order.Uint16(b))
+		if len(b) < 2+count*pcfCompressedMetricSize {
+			return nil, fmt.Errorf("pcf: truncated metrics table")
+		}
+		for i := 0; i < count; i++ {
+			p := b[2+i*pcfCompressedMetricSize:]
+			ret = append(ret, tPCFMetric{
+				lsb:     int(p[0]) - 0x80,
+				rsb:     int(p[1]) - 0x80,
+				width:   int(p[2]) - 0x80,
+				ascent:  int(p[3]) - 0x80,
+				descent: int(p[4]) - 0x80,
+			})
+		}
+	case pcfDefaultFormat:
+		if len(b) < 4 {
+			return nil, fmt.Errorf("pcf: truncated metrics table")
+		}
+		count := int(order.Uint32(b))
+		if len(b) < 4+count*pcfMetricsSize {
+			return nil, fmt.Errorf("pcf: truncated metrics table")
+		}
+		for i := 0; i < count; i++ {
+			p := b[4+i*pcfMetricsSize:]
+			ret = append(ret, tPCFMetric{
+				lsb:     int(int16(order.Uint16(p[0:]))),
+				rsb:     int(int16(order.Uint16(p[2:]))),
+				width:   int(int16(order.Uint16(p[4:]))),
+				ascent:  int(int16(order.Uint16(p[6:]))),
+				descent: int(int16(order.Uint16(p[8:]))),
+			})
+		}
+	}
+	return ret, nil
+}
+
+func reverseBits(b byte) byte {
+	b = b>>4 | b<<4
+	b = (b&0xcc)>>2 | (b&0x33)<<2
+	b = (b&0xaa)>>1 | (b&0x55)<<1
+	return b
+}
@@ -0,0 +1,162 @@
+package fontface
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"unicode/utf8"
+)
+
+const (
+	psf1Magic       = "\x36\x04"
+	psf1Mode512     = 0x01
+	psf1ModeHasTab  = 0x02
+	psf1ModeHasSeq  = 0x04
+	psf1HeaderSize  = 4
+	psf1Separator   = 0xffff
+	psf1StartSeq    = 0xfffe
+	psf2Magic       = "\x72\xb5\x4a\x86"
+	psf2HasUnicode  = 0x01
+	psf2HeaderSize  = 32
+	psf2Separator   = 0xff
+	psf2StartSeq    = 0xfe
+	psfDefaultCount = 256
+)
+
+// NewPSFFromReader - loads a Linux console PC Screen Font of version 1 or 2.
+// The whole cell lies above the baseline because the format stores no metrics.
+func NewPSFFromReader(r io.Reader) (*TFontFace, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var glyphs []tBitmap
+	switch {
+	default:
+		return nil, fmt.Errorf("psf: bad signature")
+	case len(data) >= psf1HeaderSize && string(data[:2]) == psf1Magic:
+		glyphs, err = parsePSF1(data)
+	case len(data) >= psf2HeaderSize && string(data[:4]) == psf2Magic:
+		glyphs, err = parsePSF2(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newFromBitmaps(glyphs, image.Rectangle{})
+}
+
+func parsePSF1(data []byte) ([]tBitmap, error) {
+	mode := data[2]
+	height := int(data[3])
+	count := psfDefaultCount
+	if mode&psf1Mode512 != 0 {
+		count = 512
+	}
+	masks, rest, err := psfMasks(data[psf1HeaderSize:], count, 8, height, 1)
+	if err != nil {
+		return nil, err
+	}
+	if mode&(psf1ModeHasTab|psf1ModeHasSeq) == 0 {
+		return psfIdentity(masks), nil
+	}
+
+	ret := []tBitmap{}
+	i := 0
+	inSeq := false
+	for ; len(rest) >= 2 && i < count; rest = rest[2:] {
+		v := binary.LittleEndian.Uint16(rest)
+		switch {
+		case v == psf1Separator:
+			i++
+			inSeq = false
+		case v == psf1StartSeq:
+			inSeq = true
+		case !inSeq:
+			ret = append(ret, psfGlyph(rune(v), masks[i]))
+		}
+	}
+	return ret, nil
+}
+
+func parsePSF2(data []byte) ([]tBitmap, error) {
+	le := binary.LittleEndian
+	headerSize := int(le.Uint32(data[8:]))
+	flags := le.Uint32(data[12:])
+	count := int(le.Uint32(data[16:]))
+	charSize := int(le.Uint32(data[20:]))
+	height := int(le.Uint32(data[24:]))
+	width := int(le.Uint32(data[28:]))
+	stride := (width + 7) / 8
+	if headerSize < psf2HeaderSize || headerSize > len(data) || charSize != height*stride {
+		return nil, fmt.Errorf("psf: bad header")
+	}
+	masks, rest, err := psfMasks(data[headerSize:], count, width, height, stride)
+	if err != nil {
+		return nil, err
+	}
+	if flags&psf2HasUnicode == 0 {
+		return psfIdentity(masks), nil
+	}
+
+	ret := []tBitmap{}
+	i := 0
+	inSeq := false
+	for len(rest) > 0 && i < count {
+		switch rest[0] {
+		case psf2Separator:
+			i++
+			inSeq = false
+			rest = rest[1:]
+			continue
+		case psf2StartSeq:
+			inSeq = true
+			rest = rest[1:]
+			continue
+		}
+		r, size := utf8.DecodeRune(rest)
+		rest = rest[size:]
+		if !inSeq && r != utf8.RuneError {
+			ret = append(ret, psfGlyph(r, masks[i]))
+		}
+	}
+	return ret, nil
+}
+
+func psfMasks(data []byte, count, width, height, stride int) ([]*image.Alpha, []byte, error) {
+	size := height * stride
+	if size <= 0 {
+		return nil, nil, fmt.Errorf("psf: bad glyph size %vx%v", width, height)
+	}
+	if count <= 0 || len(data)/size < count {
+		return nil, nil, fmt.Errorf("psf: truncated glyph data")
+	}
+	ret := make([]*image.Alpha, count)
+	for i := range ret {
+		mask, err := bitsToAlpha(data[i*size:], width, height, stride)
+		if err != nil {
+			return nil, nil, fmt.Errorf("psf: glyph %v: %v", i, err)
+		}
+		ret[i] = mask
+	}
+	return ret, data[count*size:], nil
+}
+
+func psfIdentity(masks []*image.Alpha) []tBitmap {
+	ret := make([]tBitmap, len(masks))
+	for i, mask := range masks {
+		ret[i] = psfGlyph(rune(i), mask)
+	}
+	return ret
+}
+
+func psfGlyph(r rune, mask *image.Alpha) tBitmap {
+	b := mask.Bounds()
+	return tBitmap{
+		r:       r,
+		mask:    mask,
+		origin:  image.Pt(0, -b.Dy()),
+		advance: b.Dx(),
+	}
+}
@@ -0,0 +1,432 @@
+package fontface
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// OS/2 fsSelection bits
+const (
+	fsSelectionItalic  = 1 << 0
+	fsSelectionBold    = 1 << 5
+	fsSelectionOblique = 1 << 9
+)
+
+// font weights (OS/2 usWeightClass)
+const (
+	WeightThin       = 100
+	WeightExtraLight = 200
+	WeightLight      = 300
+	WeightRegular    = 400
+	WeightMedium     = 500
+	WeightSemiBold   = 600
+	WeightBold       = 700
+	WeightExtraBold  = 800
+	WeightBlack      = 900
+)
+
+// font widths (OS/2 usWidthClass)
+const (
+	WidthUltraCondensed = 1 + iota
+	WidthExtraCondensed
+	WidthCondensed
+	WidthSemiCondensed
+	WidthNormal
+	WidthSemiExpanded
+	WidthExpanded
+	WidthExtraExpanded
+	WidthUltraExpanded
+)
+
+var styleWords = map[string]func(q *TFontQuery){
+	"thin":            func(q *TFontQuery) { q.Weight = WeightThin },
+	"hairline":        func(q *TFontQuery) { q.Weight = WeightThin },
+	"extralight":      func(q *TFontQuery) { q.Weight = WeightExtraLight },
+	"ultralight":      func(q *TFontQuery) { q.Weight = WeightExtraLight },
+	"light":           func(q *TFontQuery) { q.Weight = WeightLight },
+	"regular":         func(q *TFontQuery) { q.Weight = WeightRegular },
+	"normal":          func(q *TFontQuery) { q.Weight = WeightRegular },
+	"book":            func(q *TFontQuery) { q.Weight = WeightRegular },
+	"medium":          func(q *TFontQuery) { q.Weight = WeightMedium },
+	"semibold":        func(q *TFontQuery) { q.Weight = WeightSemiBold },
+	"demibold":        func(q *TFontQuery) { q.Weight = WeightSemiBold },
+	"bold":            func(q *TFontQuery) { q.Weight = WeightBold },
+	"extrabold":       func(q *TFontQuery) { q.Weight = WeightExtraBold },
+	"ultrabold":       func(q *TFontQuery) { q.Weight = WeightExtraBold },
+	"black":           func(q *TFontQuery) { q.Weight = WeightBlack },
+	"heavy":           func(q *TFontQuery) { q.Weight = WeightBlack },
+	"ultracondensed":  func(q *TFontQuery) { q.Width = WidthUltraCondensed },
+	"extracondensed":  func(q *TFontQuery) { q.Width = WidthExtraCondensed },
+	"condensed":       func(q *TFontQuery) { q.Width = WidthCondensed },
+	"semicondensed":   func(q *TFontQuery) { q.Width = WidthSemiCondensed },
+	"semiexpanded":    func(q *TFontQuery) { q.Width = WidthSemiExpanded },
+	"expanded":        func(q *TFontQuery) { q.Width = WidthExpanded },
+	"extraexpanded":   func(q *TFontQuery) { q.Width = WidthExtraExpanded },
+	"ultraexpanded":   func(q *TFontQuery) { q.Width = WidthUltraExpanded },
+	"italic":          func(q *TFontQuery) { q.Italic = true },
+	"oblique":         func(q *TFontQuery) { q.Italic = true },
+	"upright":         func(q *TFontQuery) { q.Italic = false },
+	"roman":           func(q *TFontQuery) { q.Italic = false },
+	"extra-light":     func(q *TFontQuery) { q.Weight = WeightExtraLight },
+	"semi-bold":       func(q *TFontQuery) { q.Weight = WeightSemiBold },
+	"extra-bold":      func(q *TFontQuery) { q.Weight = WeightExtraBold },
+	"semi-condensed":  func(q *TFontQuery) { q.Width = WidthSemiCondensed },
+	"extra-condensed": func(q *TFontQuery) { q.Width = WidthExtraCondensed },
+}
+
+var registryExts = map[string]bool{
+	".ttf":  true,
+	".otf":  true,
+	".ttc":  true,
+	".otc":  true,
+	".woff": true,
+}
+
+type (
+	// TFontInfo - metadata of a face in a font file.
+	TFontInfo struct {
+		Path      string
+		Index     int // of the face in a collection
+		Family    string
+		Subfamily string
+		Weight    int
+		Width     int
+		Italic    bool
+	}
+
+	// TFontQuery - zero Weight and Width mean regular and normal.
+	TFontQuery struct {
+		Family string
+		Weight int
+		Width  int
+		Italic bool
+	}
+
+	// TRegistry - fonts found in directories. Metadata of a file is parsed
+	// once and reused while the file stays unchanged, loaded faces are
+	// cached by size until Scan finds the file changed or gone.
+	TRegistry struct {
+		mu    sync.Mutex
+		files map[string]*tFileMeta
+		faces map[tFaceKey]*TFontFace
+	}
+
+	tFileMeta struct {
+		ModTime time.Time
+		Size    int64
+		Infos   []TFontInfo
+		seen    bool
+	}
+
+	tFaceKey struct {
+		path  string
+		index int
+		size  int32
+	}
+)
+
+// ParseFontQuery - parses queries like "DejaVu Sans, bold, italic". The
+// first comma separated part is the family, the rest are style words.
+func ParseFontQuery(s string) (TFontQuery, error) {
+	parts := strings.Split(s, ",")
+	q := TFontQuery{Family: strings.TrimSpace(parts[0])}
+	if q.Family == "" {
+		return q, fmt.Errorf("font query %q has no family", s)
+	}
+	for _, part := range parts[1:] {
+		for _, word := range strings.Fields(part) {
+			fn, ok := styleWords[strings.ToLower(word)]
+			if !ok {
+				return q, fmt.Errorf("font query %q: unknown style %q", s, word)
+			}
+			fn(&q)
+		}
+	}
+	return q, nil
+}
+
+// NewRegistry -
+func NewRegistry() *TRegistry {
+	return &TRegistry{
+		files: map[string]*tFileMeta{},
+		faces: map[tFaceKey]*TFontFace{},
+	}
+}
+
+// Scan - walks the directories recursively and collects vector fonts.
+// Files that cannot be parsed are skipped. Fonts from files that are gone
+// are forgotten, loaded faces of changed or gone files are dropped from the
+// cache.
+func (o *TRegistry) Scan(dirs ...string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, meta := range o.files {
+		meta.seen = false
+	}
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				if path == dir {
+					return err
+				}
+				return nil
+			}
+			if fi.IsDir() || !registryExts[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			meta, ok := o.files[path]
+			if ok && meta.ModTime.Equal(fi.ModTime()) && meta.Size == fi.Size() {
+				meta.seen = true
+				return nil
+			}
+			o.forgetFaces(path)
+			infos, err := readFontInfos(path)
+			if err != nil {
+				delete(o.files, path)
+				return nil
+			}
+			o.files[path] = &tFileMeta{ModTime: fi.ModTime(), Size: fi.Size(), Infos: infos, seen: true}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for path, meta := range o.files {
+		if !meta.seen {
+			delete(o.files, path)
+			o.forgetFaces(path)
+		}
+	}
+	return nil
+}
+
+// forgetFaces drops the loaded faces of the file from the cache, the mutex
+// must be held.
+func (o *TRegistry) forgetFaces(path string) {
+	for key := range o.faces {
+		if key.path == path {
+			delete(o.faces, key)
+		}
+	}
+}
+
+// Fonts - returns all the known faces sorted by family and path.
+func (o *TRegistry) Fonts() []TFontInfo {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	ret := []TFontInfo{}
+	for _, meta := range o.files {
+		ret = append(ret, meta.Infos...)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Family != ret[j].Family {
+			return ret[i].Family < ret[j].Family
+		}
+		if ret[i].Path != ret[j].Path {
+			return ret[i].Path < ret[j].Path
+		}
+		return ret[i].Index < ret[j].Index
+	})
+	return ret
+}
+
+// Find - returns the face of the family closest to the query style.
+func (o *TRegistry) Find(q TFontQuery) (TFontInfo, error) {
+	weight, width := q.Weight, q.Width
+	if weight == 0 {
+		weight = WeightRegular
+	}
+	if width == 0 {
+		width = WidthNormal
+	}
+	family := normFamily(q.Family)
+	best := TFontInfo{}
+	bestScore := -1
+	for _, info := range o.Fonts() {
+		if normFamily(info.Family) != family {
+			continue
+		}
+		score := abs(info.Weight-weight) + abs(info.Width-width)*1000
+		if info.Italic != q.Italic {
+			score += 100000
+		}
+		if bestScore < 0 || score < bestScore {
+			best, bestScore = info, score
+		}
+	}
+	if bestScore < 0 {
+		return best, fmt.Errorf("font family %q is not found", q.Family)
+	}
+	return best, nil
+}
+
+// Match - same as Find but takes a query string, see ParseFontQuery.
+func (o *TRegistry) Match(query string) (TFontInfo, error) {
+	q, err := ParseFontQuery(query)
+	if err != nil {
+		return TFontInfo{}, err
+	}
+	return o.Find(q)
+}
+
+// Face - resolves the query and loads the face of size pixels per em.
+func (o *TRegistry) Face(query string, size int32) (*TFontFace, error) {
+	info, err := o.Match(query)
+	if err != nil {
+		return nil, err
+	}
+	return o.Load(info, size)
+}
+
+// Load - loads the face or takes it from the cache.
+func (o *TRegistry) Load(info TFontInfo, size int32) (*TFontFace, error) {
+	key := tFaceKey{info.Path, info.Index, size}
+	o.mu.Lock()
+	face, ok := o.faces[key]
+	o.mu.Unlock()
+	if ok {
+		return face, nil
+	}
+
+	data, err := ioutil.ReadFile(info.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(data) >= 4 && string(data[:4]) == "ttcf":
+		face, err = NewCollectionFromReader(bytes.NewReader(data), info.Index, size)
+	case len(data) >= 4 && string(data[:4]) == "wOFF":
+		face, err = NewWOFFFromReader(bytes.NewReader(data), size)
+	default:
+		face, err = NewOpenTypeFromReader(bytes.NewReader(data), size)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", info.Path, err)
+	}
+
+	o.mu.Lock()
+	o.faces[key] = face
+	o.mu.Unlock()
+	return face, nil
+}
+
+// SaveCache - writes the parsed metadata as json so the next Scan of an
+// unchanged directory does not open the fonts.
+func (o *TRegistry) SaveCache(w io.Writer) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return json.NewEncoder(w).Encode(o.files)
+}
+
+// LoadCache - reads the metadata written by SaveCache.
+func (o *TRegistry) LoadCache(r io.Reader) error {
+	files := map[string]*tFileMeta{}
+	if err := json.NewDecoder(r).Decode(&files); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for path, meta := range files {
+		o.files[path] = meta
+	}
+	return nil
+}
+
+func readFontInfos(path string) ([]TFontInfo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) >= 4 && string(data[:4]) == "wOFF" {
+		data, err = decodeWOFF(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	offsets, err := sfntOffsets(data)
+	if err != nil {
+		return nil, err
+	}
+	col, err := sfnt.ParseCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	if col.NumFonts() != len(offsets) {
+		return nil, fmt.Errorf("unsupported collection")
+	}
+
+	ret := []TFontInfo{}
+	buf := &sfnt.Buffer{}
+	for i, offset := range offsets {
+		f, err := col.Font(i)
+		if err != nil {
+			return nil, err
+		}
+		info := TFontInfo{Path: path, Index: i, Weight: WeightRegular, Width: WidthNormal}
+		info.Family, _ = f.Name(buf, sfnt.NameIDTypographicFamily)
+		if info.Family == "" {
+			info.Family, _ = f.Name(buf, sfnt.NameIDFamily)
+		}
+		info.Subfamily, _ = f.Name(buf, sfnt.NameIDTypographicSubfamily)
+		if info.Subfamily == "" {
+			info.Subfamily, _ = f.Name(buf, sfnt.NameIDSubfamily)
+		}
+		if info.Family == "" {
+			return nil, fmt.Errorf("font has no family name")
+		}
+
+		tables, err := parseSFNTTables(data, offset)
+		if err != nil {
+			return nil, err
+		}
+		if os2, ok := tables.tables["OS/2"]; ok && len(os2) >= 64 {
+			info.Weight = int(binary.BigEndian.Uint16(os2[4:]))
+			info.Width = int(binary.BigEndian.Uint16(os2[6:]))
+			sel := binary.BigEndian.Uint16(os2[62:])
+			info.Italic = sel&(fsSelectionItalic|fsSelectionOblique) != 0
+			if sel&fsSelectionBold != 0 && info.Weight < WeightBold {
+				info.Weight = WeightBold
+			}
+		} else {
+			// old fonts without OS/2 rely on the subfamily name
+			q, err := ParseFontQuery(info.Family + "," + info.Subfamily)
+			if err == nil {
+				if q.Weight != 0 {
+					info.Weight = q.Weight
+				}
+				if q.Width != 0 {
+					info.Width = q.Width
+				}
+				info.Italic = q.Italic
+			}
+		}
+		ret = append(ret, info)
+	}
+	return ret, nil
+}
+
+func normFamily(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,71 @@
+package fontface
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegistryDropsStaleFaces(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "go.ttf")
+	write := func(style TGoStyle, mtime time.Time) {
+		t.Helper()
+		data, err := GoTTF(style)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func(reg *TRegistry) *TFontFace {
+		t.Helper()
+		fonts := reg.Fonts()
+		if len(fonts) != 1 {
+			t.Fatalf("fonts %+v, want one", fonts)
+		}
+		face, err := reg.Load(fonts[0], 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return face
+	}
+
+	reg := NewRegistry()
+	write(GoRegular, time.Unix(1000, 0))
+	if err := reg.Scan(dir); err != nil {
+		t.Fatal(err)
+	}
+	regular := load(reg)
+	if err := reg.Scan(dir); err != nil {
+		t.Fatal(err)
+	}
+	if load(reg) != regular {
+		t.Error("the face of an unchanged file is loaded again")
+	}
+
+	write(GoMono, time.Unix(2000, 0))
+	if err := reg.Scan(dir); err != nil {
+		t.Fatal(err)
+	}
+	mono := load(reg)
+	if mono == regular || !mono.Fixed {
+		t.Error("the face of a changed file is taken from the cache")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Scan(dir); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(reg.faces); n != 0 {
+		t.Errorf("%v faces of a removed file are cached", n)
+	}
+}
@@ -0,0 +1,506 @@
+package fontface
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// composite glyph flags
+const (
+	argsAreWords   = 0x0001
+	haveScale      = 0x0008
+	moreComponents = 0x0020
+	haveXYScale    = 0x0040
+	haveTwoByTwo   = 0x0080
+)
+
+const headCheckSumAdj = 0xb1b0afba
+
+// tables copied into a subset as is, the rest of the glyph indexed ones are
+// rebuilt and the others are dropped
+var subsetCopiedTables = map[string]bool{
+	"OS/2": true,
+	"name": true,
+	"cvt ": true,
+	"fpgm": true,
+	"prep": true,
+	"gasp": true,
+}
+
+type tSFNT struct {
+	tables map[string][]byte
+}
+
+// parseSFNTTables reads the table directory starting at offset, which is
+// not zero for fonts inside of collections.
+func parseSFNTTables(data []byte, offset int) (*tSFNT, error) {
+	if offset < 0 || len(data) < offset+sfntHeaderSize {
+		return nil, fmt.Errorf("sfnt: file is too short")
+	}
+	be := binary.BigEndian
+	numTables := int(be.Uint16(data[offset+4:]))
+	if len(data) < offset+sfntHeaderSize+numTables*sfntTableDirSize {
+		return nil, fmt.Errorf("sfnt: truncated table directory")
+	}
+	ret := &tSFNT{tables: map[string][]byte{}}
+	for i := 0; i < numTables; i++ {
+		rec := data[offset+sfntHeaderSize+i*sfntTableDirSize:]
+		start := uint64(be.Uint32(rec[8:]))
+		length := uint64(be.Uint32(rec[12:]))
+		if start+length > uint64(len(data)) {
+			return nil, fmt.Errorf("sfnt: table %q is out of bounds", rec[:4])
+		}
+		ret.tables[string(rec[:4])] = data[start : start+length]
+	}
+	return ret, nil
+}
+
+func (o *tSFNT) table(tag string, minSize int) ([]byte, error) {
+	t, ok := o.tables[tag]
+	if !ok {
+		return nil, fmt.Errorf("sfnt: %q table is missing", tag)
+	}
+	if len(t) < minSize {
+		return nil, fmt.Errorf("sfnt: %q table is too short", tag)
+	}
+	return t, nil
+}
+
+// Subset - writes to w a TrueType font that holds only the glyphs of the
+// runes. Components of composite glyphs are kept. Runes missing in the font
+// are ignored. Only fonts with TrueType outlines are supported.
+func Subset(w io.Writer, ttf []byte, runes []rune) error {
+	f, err := sfnt.Parse(ttf)
+	if err != nil {
+		return err
+	}
+	return subset(w, f, ttf, 0, runes)
+}
+
+// subset writes the subset of f, whose table directory is at offset in ttf.
+func subset(w io.Writer, f *sfnt.Font, ttf []byte, offset int, runes []rune) error {
+	be := binary.BigEndian
+	src, err := parseSFNTTables(ttf, offset)
+	if err != nil {
+		return err
+	}
+	head, err := src.table("head", 54)
+	if err != nil {
+		return err
+	}
+	maxp, err := src.table("maxp", 6)
+	if err != nil {
+		return err
+	}
+	hhea, err := src.table("hhea", 36)
+	if err != nil {
+		return err
+	}
+	hmtx, err := src.table("hmtx", 0)
+	if err != nil {
+		return err
+	}
+	loca, err := src.table("loca", 0)
+	if err != nil {
+		return err
+	}
+	glyf, err := src.table("glyf", 0)
+	if err != nil {
+		return err
+	}
+
+	numGlyphs := int(be.Uint16(maxp[4:]))
+	numHMetrics := int(be.Uint16(hhea[34:]))
+	if numHMetrics == 0 || len(hmtx) < numHMetrics*4+(numGlyphs-numHMetrics)*2 {
+		return fmt.Errorf("sfnt: bad hmtx table")
+	}
+	longLoca := be.Uint16(head[50:]) != 0
+	glyphData := func(gid int) ([]byte, error) {
+		var start, end int
+		if longLoca {
+			if len(loca) < (gid+2)*4 {
+				return nil, fmt.Errorf("sfnt: truncated loca table")
+			}
+			start, end = int(be.Uint32(loca[gid*4:])), int(be.Uint32(loca[gid*4+4:]))
+		} else {
+			if len(loca) < (gid+2)*2 {
+				return nil, fmt.Errorf("sfnt: truncated loca table")
+			}
+			start, end = int(be.Uint16(loca[gid*2:]))*2, int(be.Uint16(loca[gid*2+2:]))*2
+		}
+		if start > end || end > len(glyf) {
+			return nil, fmt.Errorf("sfnt: glyph %v is out of bounds", gid)
+		}
+		return glyf[start:end], nil
+	}
+
+	buf := &sfnt.Buffer{}
+	runeGlyph := map[rune]int{}
+	keep := map[int]bool{}
+	queue := []int{0}
+	for _, r := range runes {
+		index, err := f.GlyphIndex(buf, r)
+		if err != nil || index == 0 {
+			continue
+		}
+		runeGlyph[r] = int(index)
+		queue = append(queue, int(index))
+	}
+	// add components of composite glyphs
+	for len(queue) > 0 {
+		gid := queue[0]
+		queue = queue[1:]
+		if keep[gid] {
+			continue
+		}
+		keep[gid] = true
+		data, err := glyphData(gid)
+		if err != nil {
+			return err
+		}
+		err = walkComponents(data, func(p []byte) {
+			if c := int(be.Uint16(p)); !keep[c] {
+				queue = append(queue, c)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("sfnt: glyph %v: %v", gid, err)
+		}
+	}
+
+	oldGlyphs := []int{}
+	for gid := range keep {
+		if gid >= numGlyphs {
+			return fmt.Errorf("sfnt: glyph index %v is out of range", gid)
+		}
+		oldGlyphs = append(oldGlyphs, gid)
+	}
+	sort.Ints(oldGlyphs)
+	newIndex := map[int]int{}
+	for i, gid := range oldGlyphs {
+		newIndex[gid] = i
+	}
+
+	newGlyf := []byte{}
+	newLoca := []byte{}
+	newHmtx := []byte{}
+	for _, gid := range oldGlyphs {
+		data, err := glyphData(gid)
+		if err != nil {
+			return err
+		}
+		data = append([]byte(nil), data...)
+		walkComponents(data, func(p []byte) {
+			be.PutUint16(p, uint16(newIndex[int(be.Uint16(p))]))
+		})
+		newLoca = appendU32(newLoca, uint32(len(newGlyf)))
+		newGlyf = append(newGlyf, data...)
+		for len(newGlyf)%4 != 0 {
+			newGlyf = append(newGlyf, 0)
+		}
+
+		adv := be.Uint16(hmtx[(numHMetrics-1)*4:])
+		lsb := uint16(0)
+		if gid < numHMetrics {
+			adv = be.Uint16(hmtx[gid*4:])
+			lsb = be.Uint16(hmtx[gid*4+2:])
+		} else {
+			lsb = be.Uint16(hmtx[numHMetrics*4+(gid-numHMetrics)*2:])
+		}
+		newHmtx = appendU16(appendU16(newHmtx, adv), lsb)
+	}
+	newLoca = appendU32(newLoca, uint32(len(newGlyf)))
+
+	out := map[string][]byte{
+		"glyf": newGlyf,
+		"loca": newLoca,
+		"hmtx": newHmtx,
+		"cmap": buildCmap(runeGlyph, newIndex),
+	}
+	out["head"] = append([]byte(nil), head...)
+	be.PutUint32(out["head"][8:], 0) // checkSumAdjustment
+	be.PutUint16(out["head"][50:], 1)
+	out["maxp"] = append([]byte(nil), maxp...)
+	be.PutUint16(out["maxp"][4:], uint16(len(oldGlyphs)))
+	out["hhea"] = append([]byte(nil), hhea...)
+	be.PutUint16(out["hhea"][34:], uint16(len(oldGlyphs)))
+	if post, ok := src.tables["post"]; ok && len(post) >= 32 {
+		// version 3 has no glyph names
+		out["post"] = append([]byte(nil), post[:32]...)
+		be.PutUint32(out["post"], 0x00030000)
+	}
+	if kern, ok := src.tables["kern"]; ok {
+		if t := subsetKern(kern, newIndex); t != nil {
+			out["kern"] = t
+		}
+	}
+	for tag := range subsetCopiedTables {
+		if t, ok := src.tables[tag]; ok {
+			out[tag] = t
+		}
+	}
+	_, err = w.Write(writeSFNT(out))
+	return err
+}
+
+// Subset - writes the source font of the face reduced to the runes, see
+// the package level Subset.
+func (o *TFontFace) Subset(w io.Writer, runes []rune) error {
+	if !o.HasOutlines() {
+		return fmt.Errorf("font face has no outlines")
+	}
+	return subset(w, o.outlines, o.source, o.sourceOffset, runes)
+}
+
+// walkComponents calls fn with the glyph index field of every component of
+// a composite glyph. Simple glyphs are left alone.
+func walkComponents(data []byte, fn func(p []byte)) error {
+	if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+	for p := 10; ; {
+		if len(data) < p+4 {
+			return fmt.Errorf("truncated composite glyph")
+		}
+		flags := binary.BigEndian.Uint16(data[p:])
+		fn(data[p+2 : p+4])
+		p += 4
+		if flags&argsAreWords != 0 {
+			p += 4
+		} else {
+			p += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			p += 2
+		case flags&haveXYScale != 0:
+			p += 4
+		case flags&haveTwoByTwo != 0:
+			p += 8
+		}
+		if flags&moreComponents == 0 {
+			return nil
+		}
+	}
+}
+
+// buildCmap writes a cmap with a format 4 subtable for the BMP and a format
+// 12 one for all the runes.
+func buildCmap(runeGlyph map[rune]int, newIndex map[int]int) []byte {
+	runes := []rune{}
+	for r := range runeGlyph {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	// runs of consecutive runes mapped to consecutive glyphs
+	type tGroup struct {
+		start, end rune
+		gid        int
+	}
+	groups := []tGroup{}
+	bmp := []tGroup{}
+	for _, r := range runes {
+		gid := newIndex[runeGlyph[r]]
+		if n := len(groups); n > 0 && groups[n-1].end+1 == r && groups[n-1].gid+int(r-groups[n-1].start) == gid {
+			groups[n-1].end = r
+		} else {
+			groups = append(groups, tGroup{r, r, gid})
+		}
+		if r > 0xffff {
+			continue
+		}
+		if n := len(bmp); n > 0 && bmp[n-1].end+1 == r && bmp[n-1].gid+int(r-bmp[n-1].start) == gid {
+			bmp[n-1].end = r
+		} else {
+			bmp = append(bmp, tGroup{r, r, gid})
+		}
+	}
+	bmp = append(bmp, tGroup{0xffff, 0xffff, 0})
+
+	segX2 := len(bmp) * 2
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= len(bmp) {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 2
+	f4 := []byte{}
+	f4 = appendU16(f4, 4)
+	f4 = appendU16(f4, uint16(16+segX2*4))
+	f4 = appendU16(f4, 0) // language
+	f4 = appendU16(f4, uint16(segX2))
+	f4 = appendU16(f4, uint16(searchRange))
+	f4 = appendU16(f4, uint16(entrySelector))
+	f4 = appendU16(f4, uint16(segX2-searchRange))
+	for _, g := range bmp {
+		f4 = appendU16(f4, uint16(g.end))
+	}
+	f4 = appendU16(f4, 0) // reservedPad
+	for _, g := range bmp {
+		f4 = appendU16(f4, uint16(g.start))
+	}
+	for _, g := range bmp {
+		delta := g.gid - int(g.start)
+		if g.start == 0xffff {
+			delta = 1
+		}
+		f4 = appendU16(f4, uint16(delta))
+	}
+	for range bmp {
+		f4 = appendU16(f4, 0) // idRangeOffset
+	}
+
+	f12 := []byte{}
+	f12 = appendU16(f12, 12)
+	f12 = appendU16(f12, 0)
+	f12 = appendU32(f12, uint32(16+len(groups)*12))
+	f12 = appendU32(f12, 0) // language
+	f12 = appendU32(f12, uint32(len(groups)))
+	for _, g := range groups {
+		f12 = appendU32(f12, uint32(g.start))
+		f12 = appendU32(f12, uint32(g.end))
+		f12 = appendU32(f12, uint32(g.gid))
+	}
+
+	ret := []byte{}
+	ret = appendU16(ret, 0) // version
+	ret = appendU16(ret, 2)
+	ret = appendU16(appendU16(ret, 3), 1) // windows, unicode BMP
+	ret = appendU32(ret, 4+2*8)
+	ret = appendU16(appendU16(ret, 3), 10) // windows, unicode full
+	ret = appendU32(ret, uint32(4+2*8+len(f4)))
+	ret = append(ret, f4...)
+	return append(ret, f12...)
+}
+
+// subsetKern keeps the pairs of the first horizontal format 0 subtable of
+// a version 0 kern table whose both glyphs survive.
+func subsetKern(kern []byte, newIndex map[int]int) []byte {
+	be := binary.BigEndian
+	if len(kern) < 4 || be.Uint16(kern) != 0 {
+		return nil
+	}
+	type tPair struct {
+		left, right int
+		value       uint16
+	}
+	pairs := []tPair{}
+	p := 4
+	for n := int(be.Uint16(kern[2:])); n > 0 && len(kern) >= p+6; n-- {
+		length := int(be.Uint16(kern[p+2:]))
+		coverage := be.Uint16(kern[p+4:])
+		if coverage>>8 != 0 || coverage&1 == 0 || len(kern) < p+14 {
+			p += length
+			continue
+		}
+		nPairs := int(be.Uint16(kern[p+6:]))
+		for i := 0; i < nPairs && len(kern) >= p+14+i*6+6; i++ {
+			pair := kern[p+14+i*6:]
+			l, lok := newIndex[int(be.Uint16(pair))]
+			r, rok := newIndex[int(be.Uint16(pair[2:]))]
+			if lok && rok {
+				pairs = append(pairs, tPair{l, r, be.Uint16(pair[4:])})
+			}
+		}
+		break
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].left<<16|pairs[i].right < pairs[j].left<<16|pairs[j].right
+	})
+
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= len(pairs) {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 6
+	ret := []byte{}
+	ret = appendU16(ret, 0) // version
+	ret = appendU16(ret, 1)
+	ret = appendU16(ret, 0) // subtable version
+	ret = appendU16(ret, uint16(14+len(pairs)*6))
+	ret = appendU16(ret, 1) // horizontal, format 0
+	ret = appendU16(ret, uint16(len(pairs)))
+	ret = appendU16(ret, uint16(searchRange))
+	ret = appendU16(ret, uint16(entrySelector))
+	ret = appendU16(ret, uint16(len(pairs)*6-searchRange))
+	for _, pair := range pairs {
+		ret = appendU16(ret, uint16(pair.left))
+		ret = appendU16(ret, uint16(pair.right))
+		ret = appendU16(ret, pair.value)
+	}
+	return ret
+}
+
+// writeSFNT lays the tables out in a TrueType file and fixes the checksums.
+func writeSFNT(tables map[string][]byte) []byte {
+	be := binary.BigEndian
+	tags := []string{}
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= len(tags) {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= sfntTableDirSize
+	out := make([]byte, sfntHeaderSize+len(tags)*sfntTableDirSize)
+	be.PutUint32(out[0:], 0x00010000)
+	be.PutUint16(out[4:], uint16(len(tags)))
+	be.PutUint16(out[6:], uint16(searchRange))
+	be.PutUint16(out[8:], uint16(entrySelector))
+	be.PutUint16(out[10:], uint16(len(tags)*sfntTableDirSize-searchRange))
+
+	headOffset := 0
+	for i, tag := range tags {
+		t := tables[tag]
+		rec := out[sfntHeaderSize+i*sfntTableDirSize:]
+		copy(rec, tag)
+		be.PutUint32(rec[4:], checkSum(t))
+		be.PutUint32(rec[8:], uint32(len(out)))
+		be.PutUint32(rec[12:], uint32(len(t)))
+		if tag == "head" {
+			headOffset = len(out)
+		}
+		out = append(out, t...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	be.PutUint32(out[headOffset+8:], headCheckSumAdj-checkSum(out))
+	return out
+}
+
+func checkSum(b []byte) uint32 {
+	sum := uint32(0)
+	for i := 0; i < len(b); i += 4 {
+		v := uint32(0)
+		for j := 0; j < 4; j++ {
+			v <<= 8
+			if i+j < len(b) {
+				v |= uint32(b[i+j])
+			}
+		}
+		sum += v
+	}
+	return sum
+}
+
+func appendU16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendU32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
@@ -0,0 +1,187 @@
+package fontface
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+const argsAreXYValues = 0x0002
+
+// testCompositeTTF rebuilds goregular, which has no composite glyphs, with
+// 'Ä' made of the glyph of 'A' and the glyph of '.' moved up.
+func testCompositeTTF(t *testing.T) []byte {
+	t.Helper()
+	be := binary.BigEndian
+	src, err := parseSFNTTables(goregular.TTF, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	glyph := func(r rune) uint16 {
+		index, err := f.GlyphIndex(&sfnt.Buffer{}, r)
+		if err != nil || index == 0 {
+			t.Fatalf("no glyph for %q: %v", r, err)
+		}
+		return uint16(index)
+	}
+	composite := []byte{0xff, 0xff, 0, 0, 0, 0, 0x05, 0, 0x06, 0}
+	composite = appendU16(composite, argsAreWords|argsAreXYValues|moreComponents)
+	composite = appendU16(appendU16(appendU16(composite, glyph('A')), 0), 0)
+	composite = appendU16(composite, argsAreWords|argsAreXYValues)
+	composite = appendU16(appendU16(appendU16(composite, glyph('.')), 100), 1500)
+
+	head, loca, glyf := src.tables["head"], src.tables["loca"], src.tables["glyf"]
+	if be.Uint16(head[50:]) != 0 {
+		t.Fatal("want the short loca of goregular")
+	}
+	newGlyf, newLoca := []byte{}, []byte{}
+	for gid := 0; gid < f.NumGlyphs(); gid++ {
+		data := glyf[int(be.Uint16(loca[gid*2:]))*2 : int(be.Uint16(loca[gid*2+2:]))*2]
+		if gid == int(glyph('Ä')) {
+			data = composite
+		}
+		newLoca = appendU32(newLoca, uint32(len(newGlyf)))
+		newGlyf = append(newGlyf, data...)
+	}
+	newLoca = appendU32(newLoca, uint32(len(newGlyf)))
+
+	tables := map[string][]byte{}
+	for tag, t := range src.tables {
+		tables[tag] = t
+	}
+	tables["head"] = append([]byte(nil), head...)
+	be.PutUint16(tables["head"][50:], 1)
+	tables["loca"] = newLoca
+	tables["glyf"] = newGlyf
+	return writeSFNT(tables)
+}
+
+// testCollection wraps ttf into a collection of one font, so that its
+// table directory is not at the start of the file.
+func testCollection(ttf []byte) []byte {
+	const offset = 16
+	be := binary.BigEndian
+	ret := []byte("ttcf\x00\x01\x00\x00\x00\x00\x00\x01\x00\x00\x00\x10")
+	ret = append(ret, ttf...)
+	for i := 0; i < int(be.Uint16(ttf[4:])); i++ {
+		rec := ret[offset+sfntHeaderSize+i*sfntTableDirSize:]
+		be.PutUint32(rec[8:], be.Uint32(rec[8:])+offset)
+	}
+	return ret
+}
+
+func TestSubset(t *testing.T) {
+	ttf := testCompositeTTF(t)
+	src, err := sfnt.Parse(ttf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runes := []rune("aÄ")
+	tests := []struct {
+		name   string
+		subset func(w io.Writer) error
+	}{
+		{"font", func(w io.Writer) error { return Subset(w, ttf, runes) }},
+		{"face of a collection", func(w io.Writer) error {
+			face, err := NewCollectionFromReader(bytes.NewReader(testCollection(ttf)), 0, 16)
+			if err != nil {
+				return err
+			}
+			return face.Subset(w, runes)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			if err := tt.subset(out); err != nil {
+				t.Fatal(err)
+			}
+			got, err := sfnt.Parse(out.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			// .notdef, the runes and the components 'A' and '.'
+			if n := got.NumGlyphs(); n != 5 {
+				t.Errorf("%v glyphs, want 5", n)
+			}
+			if index, err := got.GlyphIndex(&sfnt.Buffer{}, 'A'); err != nil || index != 0 {
+				t.Errorf("'A' is mapped to %v, %v", index, err)
+			}
+			for _, r := range runes {
+				checkSubsetGlyph(t, src, got, r)
+			}
+		})
+	}
+}
+
+// checkSubsetGlyph compares the advance and the outline of r in the subset
+// got with the ones in the source font.
+func checkSubsetGlyph(t *testing.T, src, got *sfnt.Font, r rune) {
+	t.Helper()
+	ppem := fixed.I(int(src.UnitsPerEm()))
+	glyph := func(f *sfnt.Font) (fixed.Int26_6, sfnt.Segments) {
+		buf := &sfnt.Buffer{}
+		index, err := f.GlyphIndex(buf, r)
+		if err != nil || index == 0 {
+			t.Fatalf("no glyph for %q: %v", r, err)
+		}
+		adv, err := f.GlyphAdvance(buf, index, ppem, font.HintingNone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		segs, err := f.LoadGlyph(buf, index, ppem, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return adv, append(sfnt.Segments(nil), segs...)
+	}
+	wantAdv, wantSegs := glyph(src)
+	adv, segs := glyph(got)
+	if adv != wantAdv {
+		t.Errorf("%q: advance %v, want %v", r, adv, wantAdv)
+	}
+	if len(segs) == 0 || !reflect.DeepEqual(segs, wantSegs) {
+		t.Errorf("%q: outline\n%v\nwant\n%v", r, segs, wantSegs)
+	}
+}
+
+func TestWalkComponents(t *testing.T) {
+	data := []byte{0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0}
+	component := func(flags uint16, index uint16, extra int) {
+		data = appendU16(appendU16(data, flags|moreComponents), index)
+		data = append(data, make([]byte, extra)...)
+	}
+	component(0, 1, 2)
+	component(argsAreWords|haveScale, 2, 4+2)
+	component(haveXYScale, 3, 2+4)
+	component(argsAreWords|haveTwoByTwo, 4, 4+8)
+	data[len(data)-4-8-3] &^= moreComponents
+
+	got := []uint16{}
+	err := walkComponents(data, func(p []byte) { got = append(got, binary.BigEndian.Uint16(p)) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []uint16{1, 2, 3, 4}) {
+		t.Errorf("components %v, want [1 2 3 4]", got)
+	}
+	data[len(data)-4-8-3] |= moreComponents
+	if err := walkComponents(data, func([]byte) {}); err == nil {
+		t.Error("walked past the end of the glyph")
+	}
+	simple := []byte{0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if err := walkComponents(simple, func([]byte) { t.Error("walked a simple glyph") }); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,203 @@
+package fontface
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// TSVGOptions -
+type TSVGOptions struct {
+	// Color of the glyphs without their own one, black if nil.
+	Color color.Color
+	// Background fills the whole document if not nil.
+	Background color.Color
+	// EmbedFont emits <text> elements with a subset of the font embedded as
+	// a data URI instead of outline paths. The text stays selectable and
+	// searchable.
+	EmbedFont bool
+}
+
+// WriteSVG - writes the laid out glyphs (see Layout) as an SVG document of
+// the same size in pixels. Faces without outlines are written as images.
+func WriteSVG(w io.Writer, face *TFontFace, glyphs []TGlyph, opts *TSVGOptions) error {
+	if opts == nil {
+		opts = &TSVGOptions{}
+	}
+	defColor := opts.Color
+	if defColor == nil {
+		defColor = color.Black
+	}
+	if opts.EmbedFont && !face.HasOutlines() {
+		return fmt.Errorf("svg: font face has no outlines to embed")
+	}
+
+	bw := bufio.NewWriter(w)
+	width, height := face.LayoutBounds(glyphs)
+	fmt.Fprintf(bw, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		width, height, width, height)
+	if opts.Background != nil {
+		fmt.Fprintf(bw, `<rect width="100%%" height="100%%" %v/>`+"\n", svgFill(opts.Background))
+	}
+
+	var err error
+	switch {
+	case opts.EmbedFont:
+		err = writeSVGText(bw, face, glyphs, defColor)
+	case face.HasOutlines():
+		err = writeSVGPaths(bw, face, glyphs, defColor)
+	default:
+		err = writeSVGImages(bw, face, glyphs, defColor)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+func svgFill(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	ret := fmt.Sprintf(`fill="#%02x%02x%02x"`, n.R, n.G, n.B)
+	if n.A != 0xff {
+		ret += fmt.Sprintf(` fill-opacity="%.3g"`, float64(n.A)/0xff)
+	}
+	return ret
+}
+
+// sameColor compares the RGBA values, colors may be of types == panics on.
+func sameColor(a, b color.Color) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	return ar == br && ag == bg && ab == bb && aa == ba
+}
+
+// dotX returns the exact horizontal position of the dot of the glyph.
+func (o *TFontFace) dotX(g TGlyph) float32 {
+	x := float32(g.X + o.Origin.X)
+	if o.Phases > 1 {
+		x += float32(g.Phase) / float32(o.Phases)
+	}
+	return x
+}
+
+func svgNum(v float32) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+}
+
+func writeSVGPaths(w io.Writer, face *TFontFace, glyphs []TGlyph, defColor color.Color) error {
+	for _, g := range glyphs {
+		outline, err := face.Outline(g.Rune, face.size)
+		if err != nil || len(outline) == 0 {
+			continue
+		}
+		dx := face.dotX(g)
+		dy := float32(g.Y + face.Origin.Y)
+		pt := func(p TPoint) string {
+			return svgNum(p.X+dx) + " " + svgNum(p.Y+dy)
+		}
+		d := &strings.Builder{}
+		for _, seg := range outline {
+			switch seg.Op {
+			case MoveTo:
+				if d.Len() > 0 {
+					d.WriteString("Z")
+				}
+				d.WriteString("M" + pt(seg.Args[0]))
+			case LineTo:
+				d.WriteString("L" + pt(seg.Args[0]))
+			case QuadTo:
+				d.WriteString("Q" + pt(seg.Args[0]) + " " + pt(seg.Args[1]))
+			case CubeTo:
+				d.WriteString("C" + pt(seg.Args[0]) + " " + pt(seg.Args[1]) + " " + pt(seg.Args[2]))
+			}
+		}
+		d.WriteString("Z")
+		c := g.Color
+		if c == nil {
+			c = defColor
+		}
+		fmt.Fprintf(w, `<path %v d="%v"/>`+"\n", svgFill(c), d.String())
+	}
+	return nil
+}
+
+func writeSVGText(w io.Writer, face *TFontFace, glyphs []TGlyph, defColor color.Color) error {
+	runes := []rune{}
+	for _, g := range glyphs {
+		runes = append(runes, g.Rune)
+	}
+	src := &bytes.Buffer{}
+	if err := face.Subset(src, runes); err != nil {
+		return fmt.Errorf("svg: %v", err)
+	}
+	fmt.Fprintf(w, "<style>@font-face{font-family:\"embedded\";src:url(data:font/ttf;base64,%v)}</style>\n",
+		base64.StdEncoding.EncodeToString(src.Bytes()))
+	fmt.Fprintf(w, `<g font-family="embedded" font-size="%v" xml:space="preserve">`+"\n", svgNum(face.size))
+
+	// one <text> element per run of glyphs sharing a line and a color
+	for i := 0; i < len(glyphs); {
+		j := i + 1
+		for j < len(glyphs) && glyphs[j].Y == glyphs[i].Y && sameColor(glyphs[j].Color, glyphs[i].Color) {
+			j++
+		}
+		xs := []string{}
+		text := &strings.Builder{}
+		for _, g := range glyphs[i:j] {
+			xs = append(xs, svgNum(face.dotX(g)))
+			text.WriteRune(g.Rune)
+		}
+		c := glyphs[i].Color
+		if c == nil {
+			c = defColor
+		}
+		fmt.Fprintf(w, `<text x="%v" y="%v" %v>`, strings.Join(xs, " "), glyphs[i].Y+face.Origin.Y, svgFill(c))
+		if err := xml.EscapeText(w, []byte(text.String())); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "</text>\n")
+		i = j
+	}
+	fmt.Fprintf(w, "</g>\n")
+	return nil
+}
+
+func writeSVGImages(w io.Writer, face *TFontFace, glyphs []TGlyph, defColor color.Color) error {
+	for _, g := range glyphs {
+		ch := g.Char
+		if ch.Rect.Empty() {
+			continue
+		}
+		c := g.Color
+		if c == nil {
+			c = defColor
+		}
+		n := color.NRGBAModel.Convert(c).(color.NRGBA)
+		img := image.NewNRGBA(image.Rect(0, 0, ch.Rect.Dx(), ch.Rect.Dy()))
+		for y := 0; y < ch.Rect.Dy(); y++ {
+			for x := 0; x < ch.Rect.Dx(); x++ {
+				a := face.Tex.GrayAt(ch.Rect.Min.X+x, ch.Rect.Min.Y+y).Y
+				img.SetNRGBA(x, y, color.NRGBA{n.R, n.G, n.B, uint8(uint32(a) * uint32(n.A) / 0xff)})
+			}
+		}
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, img); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, `<image x="%d" y="%d" width="%d" height="%d" style="image-rendering:pixelated" href="data:image/png;base64,%v"/>`+"\n",
+			g.X+ch.Offset.X, g.Y+ch.Offset.Y, ch.Rect.Dx(), ch.Rect.Dy(),
+			base64.StdEncoding.EncodeToString(buf.Bytes()))
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package fontface
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	woffHeaderSize   = 44
+	woffTableDirSize = 20
+	sfntHeaderSize   = 12
+	sfntTableDirSize = 16
+)
+
+type tWOFFTable struct {
+	tag        uint32
+	offset     uint32
+	compLength uint32
+	origLength uint32
+	checksum   uint32
+}
+
+// decodeWOFF converts a WOFF 1.0 file into a plain sfnt (TrueType/OpenType) one.
+func decodeWOFF(data []byte) ([]byte, error) {
+	if len(data) < woffHeaderSize || string(data[:4]) != "wOFF" {
+		return nil, fmt.Errorf("woff: bad signature")
+	}
+	be := binary.BigEndian
+	flavor := be.Uint32(data[4:])
+	numTables := int(be.Uint16(data[12:]))
+	if len(data) < woffHeaderSize+numTables*woffTableDirSize {
+		return nil, fmt.Errorf("woff: truncated table directory")
+	}
+
+	tables := make([]tWOFFTable, numTables)
+	for i := range tables {
+		p := data[woffHeaderSize+i*woffTableDirSize:]
+		tables[i] = tWOFFTable{
+			tag:        be.Uint32(p[0:]),
+			offset:     be.Uint32(p[4:]),
+			compLength: be.Uint32(p[8:]),
+			origLength: be.Uint32(p[12:]),
+			checksum:   be.Uint32(p[16:]),
+		}
+	}
+	// sfnt table records must be sorted by tag
+	sort.Slice(tables, func(i, j int) bool { return tables[i].tag < tables[j].tag })
+
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= numTables {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= sfntTableDirSize
+
+	out := make([]byte, sfntHeaderSize+numTables*sfntTableDirSize)
+	be.PutUint32(out[0:], flavor)
+	be.PutUint16(out[4:], uint16(numTables))
+	be.PutUint16(out[6:], uint16(searchRange))
+	be.PutUint16(out[8:], uint16(entrySelector))
+	be.PutUint16(out[10:], uint16(numTables*sfntTableDirSize-searchRange))
+
+	for i, t := range tables {
+		end := uint64(t.offset) + uint64(t.compLength)
+		if end > uint64(len(data)) {
+			return nil, fmt.Errorf("woff: table %q is out of bounds", tagString(t.tag))
+		}
+		src := data[t.offset:end]
+		if t.compLength < t.origLength {
+			zr, err := zlib.NewReader(bytes.NewReader(src))
+			if err != nil {
+				return nil, fmt.Errorf("woff: table %q: %v", tagString(t.tag), err)
+			}
+			// origLength bounds the output, one more byte tells it was exceeded
+			src, err = io.ReadAll(io.LimitReader(zr, int64(t.origLength)+1))
+			zr.Close()
+			if err != nil {
+				return nil, fmt.Errorf("woff: table %q: %v", tagString(t.tag), err)
+			}
+		}
+		if uint32(len(src)) != t.origLength {
+			return nil, fmt.Errorf("woff: table %q: want %v bytes but got %v", tagString(t.tag), t.origLength, len(src))
+		}
+
+		rec := out[sfntHeaderSize+i*sfntTableDirSize:]
+		be.PutUint32(rec[0:], t.tag)
+		be.PutUint32(rec[4:], t.checksum)
+		be.PutUint32(rec[8:], uint32(len(out)))
+		be.PutUint32(rec[12:], t.origLength)
+
+		out = append(out, src...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out, nil
+}
+
+func tagString(tag uint32) string {
+	return string([]byte{byte(tag >> 24), byte(tag >> 16), byte(tag >> 8), byte(tag)})
+}
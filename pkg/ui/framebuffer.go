@@ -0,0 +1,161 @@
+package ui
+
+import (
+	"fmt"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+)
+
+// TFramebuffer - an offscreen render target with an RGBA8 color texture and
+// a depth/stencil renderbuffer.
+type TFramebuffer struct {
+	dev          *tDevice
+	id           uint32
+	color        *TTexture
+	depthStencil uint32
+	w, h         int
+
+	bound        bool
+	prevID       int32
+	prevViewport [4]int32
+}
+
+// NewFramebuffer -
+func NewFramebuffer(w, h int) (*TFramebuffer, error) {
+	return newFramebuffer(dev, w, h)
+}
+
+func newFramebuffer(d *tDevice, w, h int) (*TFramebuffer, error) {
+	ret := &TFramebuffer{dev: d, id: d.GenFramebuffer()}
+	if err := ret.attach(w, h); err != nil {
+		ret.Delete()
+		return nil, err
+	}
+	return ret, nil
+}
+
+func framebufferStatusString(status uint32) string {
+	switch status {
+	case gl.FRAMEBUFFER_INCOMPLETE_ATTACHMENT:
+		return "incomplete attachment"
+	case gl.FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT:
+		return "missing attachment"
+	case gl.FRAMEBUFFER_INCOMPLETE_DIMENSIONS:
+		return "attachments of different sizes"
+	case gl.FRAMEBUFFER_INCOMPLETE_MULTISAMPLE:
+		return "attachments of different sample counts"
+	case gl.FRAMEBUFFER_UNSUPPORTED:
+		return "unsupported combination of formats"
+	case gl.FRAMEBUFFER_UNDEFINED:
+		return "undefined"
+	}
+	return fmt.Sprintf("status 0x%x", status)
+}
+
+// attach replaces the attachments with new ones of the size. If the
+// framebuffer is not complete with them, the old ones stay attached.
+func (o *TFramebuffer) attach(w, h int) error {
+	color, err := newEmptyTexture(o.dev, w, h)
+	if err != nil {
+		return fmt.Errorf("framebuffer: %v", err)
+	}
+	depthStencil := o.dev.GenRenderbuffer()
+	o.dev.BindRenderbuffer(gl.RENDERBUFFER, depthStencil)
+	o.dev.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH24_STENCIL8, int32(w), int32(h))
+	o.dev.BindRenderbuffer(gl.RENDERBUFFER, 0)
+
+	prev := o.dev.getInteger(gl.FRAMEBUFFER_BINDING)
+	o.dev.BindFramebuffer(gl.FRAMEBUFFER, o.id)
+	o.attachTo(color.id, depthStencil)
+	status := o.dev.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		if o.color != nil {
+			o.attachTo(o.color.id, o.depthStencil)
+		} else {
+			o.attachTo(0, 0)
+		}
+		o.dev.BindFramebuffer(gl.FRAMEBUFFER, uint32(prev))
+		color.Delete()
+		o.dev.DeleteRenderbuffer(depthStencil)
+		return fmt.Errorf("framebuffer %vx%v is not complete: %v", w, h, framebufferStatusString(status))
+	}
+	o.dev.BindFramebuffer(gl.FRAMEBUFFER, uint32(prev))
+
+	o.detach()
+	o.color, o.depthStencil = color, depthStencil
+	o.w, o.h = w, h
+	return nil
+}
+
+// attachTo attaches the texture and the renderbuffer to the bound
+// framebuffer, 0 detaches.
+func (o *TFramebuffer) attachTo(color, depthStencil uint32) {
+	o.dev.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, color, 0)
+	o.dev.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_STENCIL_ATTACHMENT, gl.RENDERBUFFER, depthStencil)
+}
+
+func (o *TFramebuffer) detach() {
+	if o.color != nil {
+		o.color.Delete()
+		o.color = nil
+	}
+	if o.depthStencil != 0 {
+		o.dev.DeleteRenderbuffer(o.depthStencil)
+		o.depthStencil = 0
+	}
+}
+
+// Resize - recreates the attachments, the contents are lost. On errors the
+// framebuffer keeps the old size and attachments.
+func (o *TFramebuffer) Resize(w, h int) error {
+	if w == o.w && h == o.h {
+		return nil
+	}
+	if err := o.attach(w, h); err != nil {
+		return err
+	}
+	if o.bound {
+		o.dev.Viewport(0, 0, int32(w), int32(h))
+	}
+	return nil
+}
+
+// Size -
+func (o *TFramebuffer) Size() (int, int) { return o.w, o.h }
+
+// Texture - returns the color attachment, valid until Resize or Delete.
+func (o *TFramebuffer) Texture() *TTexture { return o.color }
+
+// Bind - redirects the rendering to the framebuffer and sets the viewport
+// to its size. The previous framebuffer and viewport are restored by Unbind.
+func (o *TFramebuffer) Bind() {
+	if o.bound {
+		return
+	}
+	o.prevID = o.dev.getInteger(gl.FRAMEBUFFER_BINDING)
+	o.dev.GetIntegerv(gl.VIEWPORT, o.prevViewport[:])
+	o.dev.BindFramebuffer(gl.FRAMEBUFFER, o.id)
+	o.dev.Viewport(0, 0, int32(o.w), int32(o.h))
+	o.bound = true
+}
+
+// Unbind -
+func (o *TFramebuffer) Unbind() {
+	if !o.bound {
+		return
+	}
+	o.dev.BindFramebuffer(gl.FRAMEBUFFER, uint32(o.prevID))
+	v := o.prevViewport
+	o.dev.Viewport(v[0], v[1], v[2], v[3])
+	o.bound = false
+}
+
+// Delete -
+func (o *TFramebuffer) Delete() {
+	o.Unbind()
+	o.detach()
+	if o.id != 0 {
+		o.dev.DeleteFramebuffer(o.id)
+		o.id = 0
+	}
+}
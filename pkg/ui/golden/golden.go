@@ -0,0 +1,227 @@
+// Package golden compares rendered frames with stored PNG images. Run the
+// tests with GOLDEN_UPDATE=1 to rewrite the goldens.
+package golden
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/macroblock/exp/pkg/ui"
+)
+
+// TB - the methods of testing.TB the package uses, so that it does not
+// import testing.
+type TB interface {
+	Helper()
+	Logf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Skipf(format string, args ...interface{})
+}
+
+// Dir is where the goldens are kept relative to the package under test.
+var Dir = filepath.Join("testdata", "golden")
+
+// TOptions - what counts as a mismatch. A pixel differs when some channel
+// is off by more than Tolerance and its perceptual difference is above
+// Perceptual. The images match while the share of differing pixels does
+// not exceed MaxDiffRatio.
+type TOptions struct {
+	Tolerance    uint8
+	Perceptual   float64 // 0..1, the difference of YIQ colors normalized by the maximum
+	MaxDiffRatio float64
+}
+
+// DefaultOptions - tolerates small rasterization differences of GPU drivers.
+var DefaultOptions = TOptions{
+	Tolerance:    2,
+	Perceptual:   0.05,
+	MaxDiffRatio: 0.001,
+}
+
+// TResult -
+type TResult struct {
+	Pixels     int
+	DiffPixels int
+	MaxDelta   float64 // the largest perceptual difference
+}
+
+// Ratio - share of differing pixels.
+func (o TResult) Ratio() float64 {
+	if o.Pixels == 0 {
+		return 0
+	}
+	return float64(o.DiffPixels) / float64(o.Pixels)
+}
+
+func (o TResult) String() string {
+	return fmt.Sprintf("%v of %v pixels differ (%.4f%%), max delta %.3f",
+		o.DiffPixels, o.Pixels, o.Ratio()*100, o.MaxDelta)
+}
+
+// Updating - reports whether goldens are rewritten.
+func Updating() bool {
+	return os.Getenv("GOLDEN_UPDATE") == "1"
+}
+
+// maxYIQDelta is the squared YIQ distance between black and white.
+const maxYIQDelta = 35215.0
+
+// yiqDelta is the squared difference of two colors in the YIQ space, which
+// weighs the channels close to how they are perceived. Translucent colors
+// are composed over black and over white, the larger difference wins.
+func yiqDelta(a, b color.NRGBA) float64 {
+	delta := func(bg float64) float64 {
+		blend := func(c color.NRGBA) (float64, float64, float64) {
+			k := float64(c.A) / 255
+			return bg + (float64(c.R)-bg)*k, bg + (float64(c.G)-bg)*k, bg + (float64(c.B)-bg)*k
+		}
+		r1, g1, b1 := blend(a)
+		r2, g2, b2 := blend(b)
+		y := (r1-r2)*0.29889531 + (g1-g2)*0.58662247 + (b1-b2)*0.11448223
+		i := (r1-r2)*0.59597799 - (g1-g2)*0.27417610 - (b1-b2)*0.32180189
+		q := (r1-r2)*0.21147017 - (g1-g2)*0.52261711 + (b1-b2)*0.31114694
+		return 0.5053*y*y + 0.299*i*i + 0.1957*q*q
+	}
+	return math.Max(delta(0), delta(255))
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// Compare - compares the images pixel by pixel and returns the result and
+// a diff image: differing pixels are red, the rest is a faded copy of want.
+// Images of different sizes never match.
+func Compare(got, want image.Image, opts TOptions) (TResult, *image.RGBA, error) {
+	gb, wb := got.Bounds(), want.Bounds()
+	if gb.Size() != wb.Size() {
+		return TResult{}, nil, fmt.Errorf("size %v differs from golden %v", gb.Size(), wb.Size())
+	}
+	res := TResult{Pixels: gb.Dx() * gb.Dy()}
+	diff := image.NewRGBA(image.Rect(0, 0, gb.Dx(), gb.Dy()))
+	for y := 0; y < gb.Dy(); y++ {
+		for x := 0; x < gb.Dx(); x++ {
+			a := color.NRGBAModel.Convert(got.At(gb.Min.X+x, gb.Min.Y+y)).(color.NRGBA)
+			b := color.NRGBAModel.Convert(want.At(wb.Min.X+x, wb.Min.Y+y)).(color.NRGBA)
+			d := 0.0
+			if absDiff(a.R, b.R) > opts.Tolerance || absDiff(a.G, b.G) > opts.Tolerance ||
+				absDiff(a.B, b.B) > opts.Tolerance || absDiff(a.A, b.A) > opts.Tolerance {
+				d = math.Sqrt(yiqDelta(a, b) / maxYIQDelta)
+			}
+			res.MaxDelta = math.Max(res.MaxDelta, d)
+			if d > opts.Perceptual {
+				res.DiffPixels++
+				diff.SetRGBA(x, y, color.RGBA{0xff, 0, 0, 0xff})
+				continue
+			}
+			gray := color.GrayModel.Convert(b).(color.Gray).Y
+			v := 0xff - (0xff-gray)/4
+			diff.SetRGBA(x, y, color.RGBA{v, v, v, 0xff})
+		}
+	}
+	return res, diff, nil
+}
+
+// ReadPNG -
+func ReadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+// WritePNG - creates the directories of the path if needed.
+func WritePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Assert - compares img with the golden Dir/name.png, nil opts means
+// DefaultOptions. On a mismatch name.actual.png and name.diff.png are
+// written next to the golden.
+func Assert(t TB, name string, img image.Image, opts *TOptions) {
+	t.Helper()
+	if opts == nil {
+		opts = &DefaultOptions
+	}
+	path := filepath.Join(Dir, name+".png")
+	if Updating() {
+		if err := WritePNG(path, img); err != nil {
+			t.Fatalf("golden %v: %v", name, err)
+		}
+		t.Logf("golden %v is updated", path)
+		return
+	}
+
+	want, err := ReadPNG(path)
+	if err != nil {
+		t.Fatalf("golden %v: %v (run with GOLDEN_UPDATE=1 to create it)", name, err)
+	}
+	base := strings.TrimSuffix(path, ".png")
+	res, diff, err := Compare(img, want, *opts)
+	if err == nil && res.Ratio() <= opts.MaxDiffRatio {
+		return
+	}
+	if werr := WritePNG(base+".actual.png", img); werr != nil {
+		t.Errorf("golden %v: %v", name, werr)
+	}
+	if err != nil {
+		t.Errorf("golden %v: %v, see %v.actual.png", name, err, base)
+		return
+	}
+	if werr := WritePNG(base+".diff.png", diff); werr != nil {
+		t.Errorf("golden %v: %v", name, werr)
+	}
+	t.Errorf("golden %v: %v, see %v.actual.png and %v.diff.png", name, res, base, base)
+}
+
+// Render - draws a w x h frame with a headless context and captures it. The
+// test is skipped when no GL context can be created. The frame is made
+// opaque as it is on screen, blending leaves the alpha of the offscreen
+// target below 1 where text is drawn.
+func Render(t TB, w, h int, draw func(ctx *ui.TContext)) *image.RGBA {
+	t.Helper()
+	ctx, err := ui.NewHeadlessContext(w, h)
+	if err != nil {
+		t.Skipf("headless context: %v", err)
+	}
+	defer ctx.Close()
+	ctx.Draw()
+	draw(ctx)
+	img, err := ctx.Capture()
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	return img
+}
+
+// AssertRender - Render followed by Assert.
+func AssertRender(t TB, name string, w, h int, draw func(ctx *ui.TContext), opts *TOptions) {
+	t.Helper()
+	Assert(t, name, Render(t, w, h, draw), opts)
+}
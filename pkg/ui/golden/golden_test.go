@@ -0,0 +1,175 @@
+package golden
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestYIQDelta(t *testing.T) {
+	tests := []struct {
+		a, b color.NRGBA
+		want float64 // normalized
+	}{
+		// maxYIQDelta is the distance of the farthest colors, black and
+		// white are a bit closer
+		{color.NRGBA{0, 0, 0, 255}, color.NRGBA{255, 255, 255, 255}, 0.9659},
+		{color.NRGBA{10, 20, 30, 255}, color.NRGBA{10, 20, 30, 255}, 0},
+		// invisible colors are equal whatever their RGB is
+		{color.NRGBA{255, 0, 0, 0}, color.NRGBA{0, 0, 255, 0}, 0},
+		// translucent white differs from transparent over black
+		{color.NRGBA{255, 255, 255, 0}, color.NRGBA{255, 255, 255, 51}, 0.1932},
+		// and translucent black over white
+		{color.NRGBA{0, 0, 0, 0}, color.NRGBA{0, 0, 0, 51}, 0.1932},
+	}
+	for _, tt := range tests {
+		got := math.Sqrt(yiqDelta(tt.a, tt.b) / maxYIQDelta)
+		if math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("yiqDelta(%v, %v) = %.4f, want %.4f", tt.a, tt.b, got, tt.want)
+		}
+		if back := math.Sqrt(yiqDelta(tt.b, tt.a) / maxYIQDelta); back != got {
+			t.Errorf("yiqDelta(%v, %v) is not symmetric: %v, %v", tt.a, tt.b, got, back)
+		}
+	}
+}
+
+func fill(w, h int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestCompare(t *testing.T) {
+	gray := color.NRGBA{128, 128, 128, 255}
+	with := func(img *image.NRGBA, x, y int, c color.NRGBA) *image.NRGBA {
+		img.SetNRGBA(x, y, c)
+		return img
+	}
+	tests := []struct {
+		name      string
+		got, want image.Image
+		opts      TOptions
+		diff      int
+		err       bool
+	}{
+		{"equal", fill(4, 4, gray), fill(4, 4, gray), DefaultOptions, 0, false},
+		{"sizes", fill(4, 4, gray), fill(4, 5, gray), DefaultOptions, 0, true},
+		{"within tolerance", with(fill(4, 4, gray), 1, 1, color.NRGBA{130, 126, 128, 255}),
+			fill(4, 4, gray), DefaultOptions, 0, false},
+		{"beyond tolerance", with(fill(4, 4, gray), 1, 1, color.NRGBA{131, 128, 128, 255}),
+			fill(4, 4, gray), TOptions{Tolerance: 2}, 1, false},
+		{"below perceptual", with(fill(4, 4, gray), 1, 1, color.NRGBA{131, 128, 128, 255}),
+			fill(4, 4, gray), DefaultOptions, 0, false},
+		{"black on white", with(fill(4, 4, color.White), 2, 3, color.NRGBA{0, 0, 0, 255}),
+			fill(4, 4, color.White), DefaultOptions, 1, false},
+		{"invisible", fill(4, 4, color.NRGBA{255, 0, 0, 0}), fill(4, 4, color.NRGBA{0, 255, 0, 0}),
+			DefaultOptions, 0, false},
+		{"offset bounds", fill(4, 4, gray).SubImage(image.Rect(1, 1, 3, 3)), fill(2, 2, gray),
+			DefaultOptions, 0, false},
+	}
+	for _, tt := range tests {
+		res, diff, err := Compare(tt.got, tt.want, tt.opts)
+		if (err != nil) != tt.err {
+			t.Errorf("%v: error %v", tt.name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if res.DiffPixels != tt.diff {
+			t.Errorf("%v: %v, want %v differing pixels", tt.name, res, tt.diff)
+		}
+		if res.Pixels != tt.want.Bounds().Dx()*tt.want.Bounds().Dy() {
+			t.Errorf("%v: %v pixels compared", tt.name, res.Pixels)
+		}
+		red := 0
+		for i := 0; i < len(diff.Pix); i += 4 {
+			if diff.Pix[i] == 0xff && diff.Pix[i+1] == 0 {
+				red++
+			}
+		}
+		if red != tt.diff {
+			t.Errorf("%v: %v red pixels in the diff, want %v", tt.name, red, tt.diff)
+		}
+	}
+}
+
+// tFakeT records the failures of Assert, Fatalf stops the caller like
+// testing.T does.
+type tFakeT struct {
+	errors []string
+	fatal  bool
+}
+
+type tFatal struct{}
+
+func (o *tFakeT) Helper()                                  {}
+func (o *tFakeT) Logf(format string, args ...interface{})  {}
+func (o *tFakeT) Skipf(format string, args ...interface{}) { panic(tFatal{}) }
+func (o *tFakeT) Errorf(format string, args ...interface{}) {
+	o.errors = append(o.errors, fmt.Sprintf(format, args...))
+}
+func (o *tFakeT) Fatalf(format string, args ...interface{}) {
+	o.Errorf(format, args...)
+	o.fatal = true
+	panic(tFatal{})
+}
+
+func (o *tFakeT) run(fn func(t TB)) (ret *tFakeT) {
+	ret = o
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(tFatal); !ok {
+				panic(r)
+			}
+		}
+	}()
+	fn(o)
+	return
+}
+
+func TestAssert(t *testing.T) {
+	prev := Dir
+	Dir = t.TempDir()
+	defer func() { Dir = prev }()
+	img := fill(8, 8, color.NRGBA{200, 100, 50, 255})
+	path := func(suffix string) string { return filepath.Join(Dir, "img"+suffix) }
+
+	if ft := (&tFakeT{}).run(func(t TB) { Assert(t, "img", img, nil) }); !ft.fatal {
+		t.Error("a missing golden does not fail")
+	}
+
+	t.Setenv("GOLDEN_UPDATE", "1")
+	if ft := (&tFakeT{}).run(func(t TB) { Assert(t, "img", img, nil) }); len(ft.errors) != 0 {
+		t.Fatalf("update: %v", ft.errors)
+	}
+	if _, err := os.Stat(path(".png")); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GOLDEN_UPDATE", "")
+	if ft := (&tFakeT{}).run(func(t TB) { Assert(t, "img", img, nil) }); len(ft.errors) != 0 {
+		t.Errorf("same image: %v", ft.errors)
+	}
+	if _, err := os.Stat(path(".actual.png")); !os.IsNotExist(err) {
+		t.Errorf("a match leaves %v.actual.png", "img")
+	}
+
+	other := fill(8, 8, color.NRGBA{0, 100, 50, 255})
+	if ft := (&tFakeT{}).run(func(t TB) { Assert(t, "img", other, nil) }); len(ft.errors) != 1 || ft.fatal {
+		t.Errorf("other image: %v", ft.errors)
+	}
+	for _, suffix := range []string{".actual.png", ".diff.png"} {
+		if _, err := os.Stat(path(suffix)); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package golden_test
+
+import (
+	"testing"
+
+	"github.com/macroblock/exp/pkg/ui"
+	"github.com/macroblock/exp/pkg/ui/fontface"
+	"github.com/macroblock/exp/pkg/ui/golden"
+)
+
+// TestAssertRenderText draws white text over the dark theme color. The
+// golden is what soft.TText draws for the same frame, see the tests there.
+func TestAssertRenderText(t *testing.T) {
+	face, err := fontface.Go(fontface.GoRegular, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden.AssertRender(t, "text", 160, 24, func(ctx *ui.TContext) {
+		text := ui.NewTextWithFace(face)
+		text.SetTextColor(1, 1, 1, 1)
+		text.RenderText("Golden text 0123", 4, 2, 160, 24)
+	}, nil)
+}
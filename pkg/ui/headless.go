@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// NewHeadlessContext - creates a context with a hidden window that renders
+// into an offscreen framebuffer of w x h pixels, see Capture. Without a
+// display (e.g. on CI) SDL is switched to its offscreen video driver, which
+// creates a pbuffer through EGL and works with Mesa llvmpipe. Set
+// SDL_VIDEODRIVER to override the choice.
+func NewHeadlessContext(w, h int) (*TContext, error) {
+	if os.Getenv("SDL_VIDEODRIVER") == "" && !hasDisplay() {
+		os.Setenv("SDL_VIDEODRIVER", "offscreen")
+	}
+	return newContext("headless", w, h, true)
+}
+
+func hasDisplay() bool {
+	if runtime.GOOS != "linux" && runtime.GOOS != "freebsd" {
+		return true
+	}
+	return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
+
+// setHeadlessAttributes asks for OpenGL ES 3.0, the profile EGL pbuffers of
+// software renderers provide.
+func setHeadlessAttributes() error {
+	attrs := []struct {
+		attr sdl.GLattr
+		val  int
+	}{
+		{sdl.GL_CONTEXT_PROFILE_MASK, sdl.GL_CONTEXT_PROFILE_ES},
+		{sdl.GL_CONTEXT_MAJOR_VERSION, 3},
+		{sdl.GL_CONTEXT_MINOR_VERSION, 0},
+	}
+	for _, a := range attrs {
+		if err := sdl.GLSetAttribute(a.attr, a.val); err != nil {
+			return logErrorf("sdl.GLSetAttribute: %v", err)
+		}
+	}
+	return nil
+}
+
+// Headless - reports whether the context renders offscreen.
+func (o *TContext) Headless() bool {
+	return o.target != nil
+}
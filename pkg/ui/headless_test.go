@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/macroblock/exp/pkg/ui/theme"
+)
+
+func TestHeadlessContext(t *testing.T) {
+	ctx, err := NewHeadlessContext(32, 16)
+	if err != nil {
+		t.Skipf("no offscreen driver: %v", err)
+	}
+	defer ctx.Close()
+	if !ctx.Headless() {
+		t.Fatal("the context is not headless")
+	}
+
+	ctx.Draw()
+	want := color.RGBAModel.Convert(theme.Default.GetPalette()[theme.Dark]).(color.RGBA)
+	check := func(name string, img interface {
+		RGBAAt(x, y int) color.RGBA
+	}, w, h int) {
+		t.Helper()
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				c := img.RGBAAt(x, y)
+				if absDiff8(c.R, want.R) > 1 || absDiff8(c.G, want.G) > 1 || absDiff8(c.B, want.B) > 1 {
+					t.Fatalf("%v: pixel %v,%v is %v, want the clear color %v", name, x, y, c, want)
+				}
+			}
+		}
+	}
+
+	img, err := ctx.Capture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := img.Bounds().Size(); s.X != 32 || s.Y != 16 {
+		t.Fatalf("captured %v, want 32x16", s)
+	}
+	check("Capture", img, 32, 16)
+
+	pending, err := ctx.CaptureAsync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err = pending.Image()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("CaptureAsync", img, 32, 16)
+}
+
+func absDiff8(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -1,16 +1,12 @@
 package ui
 
 import (
-	"C"
 	"fmt"
-	"strings"
 
 	gl "github.com/go-gl/gl/v3.1/gles2"
 	"github.com/macroblock/imed/pkg/misc"
 )
 
-var globActiveProgram *TProgram
-
 type (
 	// TShader -
 	TShader struct {
@@ -22,9 +18,13 @@ type (
 	TFragmentShader TShader
 	// TProgram -
 	TProgram struct {
+		dev      *tDevice
 		id       uint32
 		attribs  []TAttribParams
 		uniforms []TAttribParams
+		blocks   []TUniformBlock
+
+		uniformMap map[string]TAttribParams
 	}
 
 	// TAttribParams -
@@ -38,7 +38,7 @@ type (
 
 // NewShader -
 func NewShader(shaderType uint32, src string) (TShader, error) {
-	id, err := createShader(shaderType, src)
+	id, err := dev.createShader(shaderType, src)
 	return TShader{id: id}, err
 }
 
@@ -56,6 +56,7 @@ func NewFragmentShader(src string) (TFragmentShader, error) {
 
 // NewProgram -
 func NewProgram(vshader interface{}, fshader interface{}) (*TProgram, error) {
+	d := dev
 	var err error
 	var vs TVertexShader
 	var fs TFragmentShader
@@ -87,26 +88,27 @@ func NewProgram(vshader interface{}, fshader interface{}) (*TProgram, error) {
 		fs = *shader
 	}
 
-	ret := &TProgram{}
-	ret.id, err = createProgram(vs.id, fs.id)
+	ret := &TProgram{dev: d}
+	ret.id, err = d.createProgram(vs.id, fs.id)
 	if err != nil {
 		return nil, err
 	}
 
 	ret.attribs = ret.AttribParams()
 	ret.uniforms = ret.UniformParams()
+	ret.blocks = ret.UniformBlocks()
 	return ret, err
 }
 
 // Use -
 func (o *TProgram) Use() {
-	globActiveProgram = o
-	gl.UseProgram(o.id)
+	o.dev.activeProgram = o
+	o.dev.UseProgram(o.id)
 }
 
 // AttribLocation -
 func (o *TProgram) AttribLocation(name string) (uint32, error) {
-	index := gl.GetAttribLocation(o.id, gl.Str(name+"\x00"))
+	index := o.dev.GetAttribLocation(o.id, name)
 	if index == -1 {
 		return uint32(index), fmt.Errorf("attribute index %q at program %v is not found", name, o.id)
 	}
@@ -115,7 +117,7 @@ func (o *TProgram) AttribLocation(name string) (uint32, error) {
 
 // UniformLocation -
 func (o *TProgram) UniformLocation(name string) (uint32, error) {
-	index := gl.GetUniformLocation(o.id, gl.Str(name+"\x00"))
+	index := o.dev.GetUniformLocation(o.id, name)
 	if index == -1 {
 		return uint32(index), fmt.Errorf("uniform index %q at program %v is not found", name, o.id)
 	}
@@ -124,20 +126,14 @@ func (o *TProgram) UniformLocation(name string) (uint32, error) {
 
 // AttribParams -
 func (o *TProgram) AttribParams() []TAttribParams {
-	var count, bufSize, nameLength, maxNameLength int32
 	if o.attribs != nil {
 		return o.attribs
 	}
-	gl.GetProgramiv(o.id, gl.ACTIVE_ATTRIBUTES, &count)
-	count = int32(misc.MaxInt(0, int(count)))
-	gl.GetProgramiv(o.id, gl.ACTIVE_ATTRIBUTE_MAX_LENGTH, &maxNameLength)
-	buf := make([]byte, maxNameLength)
-	bufSize = int32(len(buf))
+	count := misc.MaxInt(0, int(o.dev.GetProgrami(o.id, gl.ACTIVE_ATTRIBUTES)))
 	ret := []TAttribParams{}
 	for i := uint32(0); i < uint32(count); i++ {
 		params := TAttribParams{}
-		gl.GetActiveAttrib(o.id, uint32(i), bufSize, &nameLength, &params.num, &params.typ, &buf[0])
-		params.name = string(buf[:nameLength])
+		params.name, params.num, params.typ = o.dev.GetActiveAttrib(o.id, i)
 		index, err := o.AttribLocation(params.name)
 		if err != nil {
 			panic(err)
@@ -148,78 +144,57 @@ func (o *TProgram) AttribParams() []TAttribParams {
 	return ret
 }
 
-// UniformParams -
+// UniformParams - the uniforms outside blocks, see UniformBlocks for the rest.
 func (o *TProgram) UniformParams() []TAttribParams {
-	var count, bufSize, nameLength, maxNameLength int32
 	if o.uniforms != nil {
 		return o.uniforms
 	}
-	gl.GetProgramiv(o.id, gl.ACTIVE_UNIFORMS, &count)
-	count = int32(misc.MaxInt(0, int(count)))
-	gl.GetProgramiv(o.id, gl.ACTIVE_UNIFORM_MAX_LENGTH, &maxNameLength)
-	buf := make([]byte, maxNameLength)
-	bufSize = int32(len(buf))
+	count := misc.MaxInt(0, int(o.dev.GetProgrami(o.id, gl.ACTIVE_UNIFORMS)))
 	ret := []TAttribParams{}
 	for i := uint32(0); i < uint32(count); i++ {
 		params := TAttribParams{}
-		gl.GetActiveUniform(o.id, uint32(i), bufSize, &nameLength, &params.num, &params.typ, &buf[0])
-		params.name = string(buf[:nameLength])
-		index, err := o.UniformLocation(params.name)
-		if err != nil {
-			panic(err)
+		params.name, params.num, params.typ = o.dev.GetActiveUniform(o.id, i)
+		index := o.dev.GetUniformLocation(o.id, params.name)
+		if index == -1 {
+			// a member of a uniform block
+			continue
 		}
-		params.index = index
+		params.index = uint32(index)
 		ret = append(ret, params)
 	}
 	return ret
 }
 
-func createShader(shaderType uint32, src string) (uint32, error) {
-	shader := gl.CreateShader(shaderType)
+func (o *tDevice) createShader(shaderType uint32, src string) (uint32, error) {
+	shader := o.CreateShader(shaderType)
 	if shader == 0 {
 		// ???
-		return 0, fmt.Errorf("unable to create shader: %v", gl.GetError())
+		return 0, fmt.Errorf("unable to create shader: %v", o.GetError())
 	}
 
-	csrc, free := gl.Strs(src)
-	gl.ShaderSource(shader, 1, csrc, nil)
-	free()
-	gl.CompileShader(shader)
-
-	status := int32(0)
-	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
-	if status == gl.FALSE {
-		logLen := int32(0)
-		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLen)
-
-		log := strings.Repeat("\x00", int(logLen+1))
-		gl.GetShaderInfoLog(shader, logLen, nil, gl.Str(log))
+	o.ShaderSource(shader, src)
+	o.CompileShader(shader)
 
+	if o.GetShaderi(shader, gl.COMPILE_STATUS) == gl.FALSE {
+		log := o.GetShaderInfoLog(shader)
 		return 0, fmt.Errorf("failed to compile %v: %v", src, log)
 	}
 	return shader, nil
 }
 
-func createProgram(vShader, fShader uint32) (uint32, error) {
-	prog := gl.CreateProgram()
-	gl.AttachShader(prog, vShader)
-	gl.AttachShader(prog, fShader)
-	gl.LinkProgram(prog)
-
-	status := int32(0)
-	gl.GetProgramiv(prog, gl.LINK_STATUS, &status)
-	if status == gl.FALSE {
-		logLen := int32(0)
-		gl.GetProgramiv(prog, gl.INFO_LOG_LENGTH, &logLen)
-
-		log := strings.Repeat("\x00", int(logLen+1))
-		gl.GetProgramInfoLog(prog, logLen, nil, gl.Str(log))
+func (o *tDevice) createProgram(vShader, fShader uint32) (uint32, error) {
+	prog := o.CreateProgram()
+	o.AttachShader(prog, vShader)
+	o.AttachShader(prog, fShader)
+	o.LinkProgram(prog)
 
-		gl.DeleteProgram(prog) // ???
+	if o.GetProgrami(prog, gl.LINK_STATUS) == gl.FALSE {
+		log := o.GetProgramInfoLog(prog)
+		o.DeleteProgram(prog) // ???
 		return 0, fmt.Errorf("failed to link program %v: %v", prog, log)
 	}
-	gl.DeleteShader(vShader)
-	gl.DeleteShader(fShader)
+	o.DeleteShader(vShader)
+	o.DeleteShader(fShader)
 
 	return prog, nil
 }
@@ -0,0 +1,173 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"unsafe"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+)
+
+// TPendingCapture - pixels being copied into a pixel buffer object by the
+// GPU, see TContext.CaptureAsync. Poll Ready once a frame and take the image
+// with Image, which blocks if it is not ready yet.
+type TPendingCapture struct {
+	dev  *tDevice
+	pbo  uint32
+	sync uintptr
+	w, h int
+}
+
+// flipRows turns the bottom-up rows GL returns into a top-down image.
+func flipRows(pix []uint8, w, h int) *image.RGBA {
+	ret := image.NewRGBA(image.Rect(0, 0, w, h))
+	stride := w * 4
+	for y := 0; y < h; y++ {
+		copy(ret.Pix[y*ret.Stride:y*ret.Stride+stride], pix[(h-1-y)*stride:(h-y)*stride])
+	}
+	return ret
+}
+
+// readPixels reads the w x h area at the origin of the bound framebuffer.
+func (o *tDevice) readPixels(w, h int) (*image.RGBA, error) {
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("read pixels: invalid size %vx%v", w, h)
+	}
+	pix := make([]uint8, w*h*4)
+	o.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	o.ReadPixels(0, 0, int32(w), int32(h), gl.RGBA, gl.UNSIGNED_BYTE, unsafe.Pointer(&pix[0]))
+	if e := o.GetError(); e != gl.NO_ERROR {
+		return nil, fmt.Errorf("read pixels: gl error 0x%x", e)
+	}
+	return flipRows(pix, w, h), nil
+}
+
+// readPixelsAsync starts copying the w x h area at the origin of the bound
+// framebuffer into a new pixel buffer object.
+func (o *tDevice) readPixelsAsync(w, h int) (*TPendingCapture, error) {
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("read pixels: invalid size %vx%v", w, h)
+	}
+	ret := &TPendingCapture{dev: o, w: w, h: h}
+	ret.pbo = o.GenBuffer()
+	o.BindBuffer(gl.PIXEL_PACK_BUFFER, ret.pbo)
+	o.BufferData(gl.PIXEL_PACK_BUFFER, w*h*4, nil, gl.STREAM_READ)
+	o.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	o.ReadPixels(0, 0, int32(w), int32(h), gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	o.BindBuffer(gl.PIXEL_PACK_BUFFER, 0)
+	if e := o.GetError(); e != gl.NO_ERROR {
+		ret.Delete()
+		return nil, fmt.Errorf("read pixels: gl error 0x%x", e)
+	}
+	ret.sync = o.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+	return ret, nil
+}
+
+// Ready - reports whether the copy has finished, it never blocks.
+func (o *TPendingCapture) Ready() bool {
+	if o.sync == 0 {
+		return true
+	}
+	switch o.dev.ClientWaitSync(o.sync, gl.SYNC_FLUSH_COMMANDS_BIT, 0) {
+	case gl.ALREADY_SIGNALED, gl.CONDITION_SATISFIED:
+		return true
+	}
+	return false
+}
+
+// Image - maps the buffer and returns the top-down image, the capture is
+// deleted afterwards.
+func (o *TPendingCapture) Image() (*image.RGBA, error) {
+	if o.pbo == 0 {
+		return nil, fmt.Errorf("capture is deleted")
+	}
+	defer o.Delete()
+	size := o.w * o.h * 4
+	o.dev.BindBuffer(gl.PIXEL_PACK_BUFFER, o.pbo)
+	defer o.dev.BindBuffer(gl.PIXEL_PACK_BUFFER, 0)
+	ptr := o.dev.MapBufferRange(gl.PIXEL_PACK_BUFFER, 0, size, gl.MAP_READ_BIT)
+	if ptr == nil {
+		return nil, fmt.Errorf("capture: could not map the pixel buffer")
+	}
+	pix := (*[1 << 30]uint8)(ptr)[:size:size]
+	ret := flipRows(pix, o.w, o.h)
+	if !o.dev.UnmapBuffer(gl.PIXEL_PACK_BUFFER) {
+		return nil, fmt.Errorf("capture: pixel buffer is corrupted")
+	}
+	return ret, nil
+}
+
+// Delete - releases the buffer, needed only if Image is never called.
+func (o *TPendingCapture) Delete() {
+	if o.sync != 0 {
+		o.dev.DeleteSync(o.sync)
+		o.sync = 0
+	}
+	if o.pbo != 0 {
+		o.dev.DeleteBuffer(o.pbo)
+		o.pbo = 0
+	}
+}
+
+// withDefaultFramebuffer binds the window framebuffer, or the target of a
+// headless context, for reading while fn runs.
+func (o *TContext) withDefaultFramebuffer(fn func(w, h int)) {
+	if o.Invalid() {
+		logPanicf("invalid context\n")
+	}
+	if o.target != nil {
+		bound := o.target.bound
+		o.target.Bind()
+		fn(o.target.Size())
+		if !bound {
+			o.target.Unbind()
+		}
+		return
+	}
+	prev := o.dev.getInteger(gl.FRAMEBUFFER_BINDING)
+	o.dev.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	o.dev.ReadBuffer(gl.BACK)
+	w, h := o.window.GLGetDrawableSize()
+	fn(int(w), int(h))
+	o.dev.BindFramebuffer(gl.FRAMEBUFFER, uint32(prev))
+}
+
+// Capture - returns the frame drawn so far, call it before Flush.
+func (o *TContext) Capture() (img *image.RGBA, err error) {
+	o.withDefaultFramebuffer(func(w, h int) {
+		img, err = o.dev.readPixels(w, h)
+	})
+	return img, err
+}
+
+// CaptureAsync - same as Capture but does not wait for the GPU.
+func (o *TContext) CaptureAsync() (c *TPendingCapture, err error) {
+	o.withDefaultFramebuffer(func(w, h int) {
+		c, err = o.dev.readPixelsAsync(w, h)
+	})
+	return c, err
+}
+
+// ReadPixels - returns the contents of the color texture.
+func (o *TFramebuffer) ReadPixels() (*image.RGBA, error) {
+	bound := o.bound
+	o.Bind()
+	defer func() {
+		if !bound {
+			o.Unbind()
+		}
+	}()
+	return o.dev.readPixels(o.w, o.h)
+}
+
+// ReadPixelsAsync - same as ReadPixels but does not wait for the GPU.
+func (o *TFramebuffer) ReadPixelsAsync() (*TPendingCapture, error) {
+	bound := o.bound
+	o.Bind()
+	defer func() {
+		if !bound {
+			o.Unbind()
+		}
+	}()
+	return o.dev.readPixelsAsync(o.w, o.h)
+}
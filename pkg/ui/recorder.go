@@ -0,0 +1,634 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+)
+
+// TCommand - a call of an IDevice method. Data passed by pointer is copied
+// into a []byte for buffers and left out (nil) for pixels.
+type TCommand struct {
+	Name string
+	Args []interface{}
+}
+
+func (o TCommand) String() string {
+	args := make([]string, len(o.Args))
+	for i, arg := range o.Args {
+		switch v := arg.(type) {
+		case []byte:
+			args[i] = fmt.Sprintf("[%v bytes]", len(v))
+		case string:
+			args[i] = fmt.Sprintf("%q", v)
+		default:
+			args[i] = fmt.Sprint(v)
+		}
+	}
+	return o.Name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// TRecorder - a device without a GPU that logs the commands it gets. Names
+// of objects are counted from 1, shaders compile, programs link with the
+// attributes, uniforms and uniform blocks declared since the previous
+// program was created and every name outside a block gets a location,
+// other queries return zeros. A null recorder keeps no log.
+type TRecorder struct {
+	null     bool
+	commands []TCommand
+
+	lastID    uint32
+	bound     map[uint32]uint32 // buffer bound to a target
+	buffers   map[uint32][]byte // contents of mapped buffers
+	locations map[string]int32
+	next      *tDeclared // for the next program
+	programs  map[uint32]*tDeclared
+	bindings  map[[2]uint32]uint32
+	status    uint32 // of framebuffers
+}
+
+// tDeclared - the active variables of a program.
+type tDeclared struct {
+	attribs  []TCommand // Name is the name of the variable, Args its size and type
+	uniforms []TCommand // block members add the block, offset and strides to Args
+	blocks   []TCommand // Args are the data size and the member indices
+}
+
+// NewRecorder -
+func NewRecorder() *TRecorder {
+	return &TRecorder{
+		bound:     map[uint32]uint32{},
+		buffers:   map[uint32][]byte{},
+		locations: map[string]int32{},
+		next:      &tDeclared{},
+		programs:  map[uint32]*tDeclared{},
+		bindings:  map[[2]uint32]uint32{},
+		status:    gl.FRAMEBUFFER_COMPLETE,
+	}
+}
+
+// NewNullDevice - a recorder that drops the commands.
+func NewNullDevice() *TRecorder {
+	ret := NewRecorder()
+	ret.null = true
+	return ret
+}
+
+// DeclareAttrib - makes the next program created report an active
+// attribute, e.g. DeclareAttrib("Vertex", 1, gl.FLOAT_VEC4).
+func (o *TRecorder) DeclareAttrib(name string, size int32, xtype uint32) {
+	o.next.attribs = append(o.next.attribs, TCommand{Name: name, Args: []interface{}{size, xtype}})
+}
+
+// DeclareUniform - same as DeclareAttrib for uniforms.
+func (o *TRecorder) DeclareUniform(name string, size int32, xtype uint32) {
+	o.next.uniforms = append(o.next.uniforms, TCommand{Name: name, Args: []interface{}{size, xtype}})
+}
+
+// DeclareUniformBlock - same as DeclareAttrib for a uniform block and its
+// members, which are declared as uniforms too.
+func (o *TRecorder) DeclareUniformBlock(name string, size int, members ...TUniformMember) {
+	d := o.next
+	block := int32(len(d.blocks))
+	indices := []int32{}
+	for _, m := range members {
+		indices = append(indices, int32(len(d.uniforms)))
+		d.uniforms = append(d.uniforms, TCommand{Name: m.Name, Args: []interface{}{m.Num, m.Type,
+			block, int32(m.Offset), int32(m.ArrayStride), int32(m.MatrixStride)}})
+	}
+	d.blocks = append(d.blocks, TCommand{Name: name, Args: []interface{}{int32(size), indices}})
+}
+
+// SetFramebufferStatus - makes CheckFramebufferStatus return the status,
+// e.g. gl.FRAMEBUFFER_UNSUPPORTED to fail the framebuffers created or
+// resized afterwards.
+func (o *TRecorder) SetFramebufferStatus(status uint32) {
+	o.status = status
+}
+
+// declared returns the variables of the program, none for unknown ones.
+func (o *TRecorder) declared(program uint32) *tDeclared {
+	if d, ok := o.programs[program]; ok {
+		return d
+	}
+	return &tDeclared{}
+}
+
+// Commands - returns the log.
+func (o *TRecorder) Commands() []TCommand {
+	return o.commands
+}
+
+// Find - returns the commands of the names in the order they were issued.
+func (o *TRecorder) Find(names ...string) []TCommand {
+	ret := []TCommand{}
+	for _, c := range o.commands {
+		for _, name := range names {
+			if c.Name == name {
+				ret = append(ret, c)
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// Reset - clears the log, the names and locations given out are kept.
+func (o *TRecorder) Reset() {
+	o.commands = nil
+}
+
+func (o *TRecorder) String() string {
+	b := strings.Builder{}
+	for _, c := range o.commands {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func (o *TRecorder) record(name string, args ...interface{}) {
+	if o.null {
+		return
+	}
+	o.commands = append(o.commands, TCommand{Name: name, Args: args})
+}
+
+func (o *TRecorder) genID(name string) uint32 {
+	o.lastID++
+	o.record(name, o.lastID)
+	return o.lastID
+}
+
+func bytesAt(data unsafe.Pointer, size int) []byte {
+	if data == nil || size <= 0 {
+		return nil
+	}
+	return append([]byte(nil), (*[1 << 30]byte)(data)[:size:size]...)
+}
+
+// Init -
+func (o *TRecorder) Init() error { o.record("Init"); return nil }
+
+// GetError -
+func (o *TRecorder) GetError() uint32 { return gl.NO_ERROR }
+
+// GetString -
+func (o *TRecorder) GetString(name uint32) string { return "" }
+
+// GetIntegerv -
+func (o *TRecorder) GetIntegerv(pname uint32, data []int32) {
+	for i := range data {
+		data[i] = 0
+	}
+}
+
+// Finish -
+func (o *TRecorder) Finish() { o.record("Finish") }
+
+// Enable -
+func (o *TRecorder) Enable(capability uint32) { o.record("Enable", capability) }
+
+// Disable -
+func (o *TRecorder) Disable(capability uint32) { o.record("Disable", capability) }
+
+// BlendFunc -
+func (o *TRecorder) BlendFunc(sfactor, dfactor uint32) { o.record("BlendFunc", sfactor, dfactor) }
+
+// ColorMask -
+func (o *TRecorder) ColorMask(r, g, b, a bool) { o.record("ColorMask", r, g, b, a) }
+
+// StencilFunc -
+func (o *TRecorder) StencilFunc(fn uint32, ref int32, mask uint32) {
+	o.record("StencilFunc", fn, ref, mask)
+}
+
+// StencilMask -
+func (o *TRecorder) StencilMask(mask uint32) { o.record("StencilMask", mask) }
+
+// StencilOp -
+func (o *TRecorder) StencilOp(fail, zfail, zpass uint32) { o.record("StencilOp", fail, zfail, zpass) }
+
+// StencilOpSeparate -
+func (o *TRecorder) StencilOpSeparate(face, fail, zfail, zpass uint32) {
+	o.record("StencilOpSeparate", face, fail, zfail, zpass)
+}
+
+// Viewport -
+func (o *TRecorder) Viewport(x, y, w, h int32) { o.record("Viewport", x, y, w, h) }
+
+// ClearColor -
+func (o *TRecorder) ClearColor(r, g, b, a float32) { o.record("ClearColor", r, g, b, a) }
+
+// Clear -
+func (o *TRecorder) Clear(mask uint32) { o.record("Clear", mask) }
+
+// GenBuffer -
+func (o *TRecorder) GenBuffer() uint32 { return o.genID("GenBuffer") }
+
+// DeleteBuffer -
+func (o *TRecorder) DeleteBuffer(id uint32) {
+	delete(o.buffers, id)
+	o.record("DeleteBuffer", id)
+}
+
+// BindBuffer -
+func (o *TRecorder) BindBuffer(target, id uint32) {
+	o.bound[target] = id
+	o.record("BindBuffer", target, id)
+}
+
+// BufferData -
+func (o *TRecorder) BufferData(target uint32, size int, data unsafe.Pointer, usage uint32) {
+	o.buffers[o.bound[target]] = make([]byte, size)
+	o.record("BufferData", target, size, bytesAt(data, size), usage)
+}
+
+// BufferSubData -
+func (o *TRecorder) BufferSubData(target uint32, offset, size int, data unsafe.Pointer) {
+	o.record("BufferSubData", target, offset, size, bytesAt(data, size))
+}
+
+// MapBufferRange - maps zeros.
+func (o *TRecorder) MapBufferRange(target uint32, offset, size int, access uint32) unsafe.Pointer {
+	o.record("MapBufferRange", target, offset, size, access)
+	buf := o.buffers[o.bound[target]]
+	if offset < 0 || size <= 0 || offset+size > len(buf) {
+		return nil
+	}
+	return unsafe.Pointer(&buf[offset])
+}
+
+// UnmapBuffer -
+func (o *TRecorder) UnmapBuffer(target uint32) bool { o.record("UnmapBuffer", target); return true }
+
+// GenVertexArray -
+func (o *TRecorder) GenVertexArray() uint32 { return o.genID("GenVertexArray") }
+
+// DeleteVertexArray -
+func (o *TRecorder) DeleteVertexArray(id uint32) { o.record("DeleteVertexArray", id) }
+
+// BindVertexArray -
+func (o *TRecorder) BindVertexArray(id uint32) { o.record("BindVertexArray", id) }
+
+// EnableVertexAttribArray -
+func (o *TRecorder) EnableVertexAttribArray(index uint32) { o.record("EnableVertexAttribArray", index) }
+
+// VertexAttribPointer -
+func (o *TRecorder) VertexAttribPointer(index uint32, size int32, xtype uint32, normalized bool, stride int32, offset int) {
+	o.record("VertexAttribPointer", index, size, xtype, normalized, stride, offset)
+}
+
+// VertexAttribIPointer -
+func (o *TRecorder) VertexAttribIPointer(index uint32, size int32, xtype uint32, stride int32, offset int) {
+	o.record("VertexAttribIPointer", index, size, xtype, stride, offset)
+}
+
+// DrawArrays -
+func (o *TRecorder) DrawArrays(mode uint32, first, count int32) {
+	o.record("DrawArrays", mode, first, count)
+}
+
+// DrawElements -
+func (o *TRecorder) DrawElements(mode uint32, count int32, xtype uint32, offset int) {
+	o.record("DrawElements", mode, count, xtype, offset)
+}
+
+// GenTexture -
+func (o *TRecorder) GenTexture() uint32 { return o.genID("GenTexture") }
+
+// DeleteTexture -
+func (o *TRecorder) DeleteTexture(id uint32) { o.record("DeleteTexture", id) }
+
+// ActiveTexture -
+func (o *TRecorder) ActiveTexture(unit uint32) { o.record("ActiveTexture", unit) }
+
+// BindTexture -
+func (o *TRecorder) BindTexture(target, id uint32) { o.record("BindTexture", target, id) }
+
+// TexImage2D -
+func (o *TRecorder) TexImage2D(target uint32, level, internalFormat, w, h int32, format, xtype uint32, pix unsafe.Pointer) {
+	o.record("TexImage2D", target, level, internalFormat, w, h, format, xtype)
+}
+
+// TexSubImage2D -
+func (o *TRecorder) TexSubImage2D(target uint32, level, x, y, w, h int32, format, xtype uint32, pix unsafe.Pointer) {
+	o.record("TexSubImage2D", target, level, x, y, w, h, format, xtype)
+}
+
+// TexParameteri -
+func (o *TRecorder) TexParameteri(target, pname uint32, param int32) {
+	o.record("TexParameteri", target, pname, param)
+}
+
+// GenerateMipmap -
+func (o *TRecorder) GenerateMipmap(target uint32) { o.record("GenerateMipmap", target) }
+
+// PixelStorei -
+func (o *TRecorder) PixelStorei(pname uint32, param int32) { o.record("PixelStorei", pname, param) }
+
+// GenFramebuffer -
+func (o *TRecorder) GenFramebuffer() uint32 { return o.genID("GenFramebuffer") }
+
+// DeleteFramebuffer -
+func (o *TRecorder) DeleteFramebuffer(id uint32) { o.record("DeleteFramebuffer", id) }
+
+// BindFramebuffer -
+func (o *TRecorder) BindFramebuffer(target, id uint32) { o.record("BindFramebuffer", target, id) }
+
+// CheckFramebufferStatus - complete unless SetFramebufferStatus says else.
+func (o *TRecorder) CheckFramebufferStatus(target uint32) uint32 {
+	o.record("CheckFramebufferStatus", target)
+	return o.status
+}
+
+// FramebufferTexture2D -
+func (o *TRecorder) FramebufferTexture2D(target, attachment, texTarget, texture uint32, level int32) {
+	o.record("FramebufferTexture2D", target, attachment, texTarget, texture, level)
+}
+
+// FramebufferRenderbuffer -
+func (o *TRecorder) FramebufferRenderbuffer(target, attachment, rbTarget, renderbuffer uint32) {
+	o.record("FramebufferRenderbuffer", target, attachment, rbTarget, renderbuffer)
+}
+
+// GetFramebufferAttachmentParameteri -
+func (o *TRecorder) GetFramebufferAttachmentParameteri(target, attachment, pname uint32) int32 {
+	return 0
+}
+
+// GenRenderbuffer -
+func (o *TRecorder) GenRenderbuffer() uint32 { return o.genID("GenRenderbuffer") }
+
+// DeleteRenderbuffer -
+func (o *TRecorder) DeleteRenderbuffer(id uint32) { o.record("DeleteRenderbuffer", id) }
+
+// BindRenderbuffer -
+func (o *TRecorder) BindRenderbuffer(target, id uint32) { o.record("BindRenderbuffer", target, id) }
+
+// RenderbufferStorage -
+func (o *TRecorder) RenderbufferStorage(target, internalFormat uint32, w, h int32) {
+	o.record("RenderbufferStorage", target, internalFormat, w, h)
+}
+
+// ReadBuffer -
+func (o *TRecorder) ReadBuffer(src uint32) { o.record("ReadBuffer", src) }
+
+// ReadPixels - leaves the pixels as they are.
+func (o *TRecorder) ReadPixels(x, y, w, h int32, format, xtype uint32, pix unsafe.Pointer) {
+	o.record("ReadPixels", x, y, w, h, format, xtype)
+}
+
+// FenceSync -
+func (o *TRecorder) FenceSync(condition, flags uint32) uintptr {
+	o.lastID++
+	o.record("FenceSync", condition, flags)
+	return uintptr(o.lastID)
+}
+
+// ClientWaitSync - always signaled.
+func (o *TRecorder) ClientWaitSync(sync uintptr, flags uint32, timeout uint64) uint32 {
+	o.record("ClientWaitSync", sync, flags, timeout)
+	return gl.ALREADY_SIGNALED
+}
+
+// DeleteSync -
+func (o *TRecorder) DeleteSync(sync uintptr) { o.record("DeleteSync", sync) }
+
+// CreateShader -
+func (o *TRecorder) CreateShader(xtype uint32) uint32 { return o.genID("CreateShader") }
+
+// DeleteShader -
+func (o *TRecorder) DeleteShader(id uint32) { o.record("DeleteShader", id) }
+
+// ShaderSource -
+func (o *TRecorder) ShaderSource(id uint32, src string) { o.record("ShaderSource", id, src) }
+
+// CompileShader -
+func (o *TRecorder) CompileShader(id uint32) { o.record("CompileShader", id) }
+
+// GetShaderi - COMPILE_STATUS is TRUE, the rest is zero.
+func (o *TRecorder) GetShaderi(id, pname uint32) int32 {
+	if pname == gl.COMPILE_STATUS {
+		return gl.TRUE
+	}
+	return 0
+}
+
+// GetShaderInfoLog -
+func (o *TRecorder) GetShaderInfoLog(id uint32) string { return "" }
+
+// CreateProgram - the program takes the variables declared so far.
+func (o *TRecorder) CreateProgram() uint32 {
+	id := o.genID("CreateProgram")
+	o.programs[id], o.next = o.next, &tDeclared{}
+	return id
+}
+
+// DeleteProgram -
+func (o *TRecorder) DeleteProgram(id uint32) { o.record("DeleteProgram", id) }
+
+// AttachShader -
+func (o *TRecorder) AttachShader(program, shader uint32) { o.record("AttachShader", program, shader) }
+
+// LinkProgram -
+func (o *TRecorder) LinkProgram(id uint32) { o.record("LinkProgram", id) }
+
+// UseProgram -
+func (o *TRecorder) UseProgram(id uint32) { o.record("UseProgram", id) }
+
+// GetProgrami - LINK_STATUS is TRUE, the numbers of active variables are
+// the declared ones, the rest is zero.
+func (o *TRecorder) GetProgrami(id, pname uint32) int32 {
+	switch pname {
+	case gl.LINK_STATUS:
+		return gl.TRUE
+	case gl.ACTIVE_ATTRIBUTES:
+		return int32(len(o.declared(id).attribs))
+	case gl.ACTIVE_UNIFORMS:
+		return int32(len(o.declared(id).uniforms))
+	case gl.ACTIVE_UNIFORM_BLOCKS:
+		return int32(len(o.declared(id).blocks))
+	}
+	return 0
+}
+
+// GetProgramInfoLog -
+func (o *TRecorder) GetProgramInfoLog(id uint32) string { return "" }
+
+// GetActiveAttrib -
+func (o *TRecorder) GetActiveAttrib(program, index uint32) (string, int32, uint32) {
+	return variable(o.declared(program).attribs, index)
+}
+
+// GetActiveUniform -
+func (o *TRecorder) GetActiveUniform(program, index uint32) (string, int32, uint32) {
+	return variable(o.declared(program).uniforms, index)
+}
+
+func variable(vars []TCommand, index uint32) (string, int32, uint32) {
+	if int(index) >= len(vars) {
+		return "", 0, 0
+	}
+	v := vars[index]
+	return v.Name, v.Args[0].(int32), v.Args[1].(uint32)
+}
+
+// location gives every name of a program its own location.
+func (o *TRecorder) location(program uint32, name string) int32 {
+	key := fmt.Sprintf("%v/%v", program, name)
+	loc, ok := o.locations[key]
+	if !ok {
+		loc = int32(len(o.locations))
+		o.locations[key] = loc
+	}
+	return loc
+}
+
+// GetAttribLocation -
+func (o *TRecorder) GetAttribLocation(program uint32, name string) int32 {
+	return o.location(program, "attrib "+name)
+}
+
+// GetUniformLocation - -1 for block members.
+func (o *TRecorder) GetUniformLocation(program uint32, name string) int32 {
+	for _, u := range o.declared(program).uniforms {
+		if u.Name == name && len(u.Args) > 2 {
+			return -1
+		}
+	}
+	return o.location(program, "uniform "+name)
+}
+
+// GetActiveUniformsiv - the block, offset and strides are -1 outside blocks.
+func (o *TRecorder) GetActiveUniformsiv(program uint32, indices []uint32, pname uint32, params []int32) {
+	uniforms := o.declared(program).uniforms
+	arg := map[uint32]int{gl.UNIFORM_SIZE: 0, gl.UNIFORM_TYPE: 1, gl.UNIFORM_BLOCK_INDEX: 2,
+		gl.UNIFORM_OFFSET: 3, gl.UNIFORM_ARRAY_STRIDE: 4, gl.UNIFORM_MATRIX_STRIDE: 5}
+	for i, index := range indices {
+		params[i] = 0
+		n, ok := arg[pname]
+		if !ok || int(index) >= len(uniforms) {
+			continue
+		}
+		switch args := uniforms[index].Args; {
+		case n >= len(args):
+			params[i] = -1
+		case n == 1:
+			params[i] = int32(args[n].(uint32))
+		default:
+			params[i] = args[n].(int32)
+		}
+	}
+}
+
+// GetUniformBlockIndex -
+func (o *TRecorder) GetUniformBlockIndex(program uint32, name string) uint32 {
+	for i, b := range o.declared(program).blocks {
+		if b.Name == name {
+			return uint32(i)
+		}
+	}
+	return gl.INVALID_INDEX
+}
+
+// GetActiveUniformBlockName -
+func (o *TRecorder) GetActiveUniformBlockName(program, index uint32) string {
+	blocks := o.declared(program).blocks
+	if int(index) >= len(blocks) {
+		return ""
+	}
+	return blocks[index].Name
+}
+
+// GetActiveUniformBlockiv - the data size, the binding and the members of
+// the declared block, the rest is zero.
+func (o *TRecorder) GetActiveUniformBlockiv(program, index, pname uint32, params []int32) {
+	params[0] = 0
+	blocks := o.declared(program).blocks
+	if int(index) >= len(blocks) {
+		return
+	}
+	b := blocks[index]
+	switch pname {
+	case gl.UNIFORM_BLOCK_DATA_SIZE:
+		params[0] = b.Args[0].(int32)
+	case gl.UNIFORM_BLOCK_BINDING:
+		params[0] = int32(o.bindings[[2]uint32{program, index}])
+	case gl.UNIFORM_BLOCK_ACTIVE_UNIFORMS:
+		params[0] = int32(len(b.Args[1].([]int32)))
+	case gl.UNIFORM_BLOCK_ACTIVE_UNIFORM_INDICES:
+		copy(params, b.Args[1].([]int32))
+	}
+}
+
+// UniformBlockBinding -
+func (o *TRecorder) UniformBlockBinding(program, index, binding uint32) {
+	o.bindings[[2]uint32{program, index}] = binding
+	o.record("UniformBlockBinding", program, index, binding)
+}
+
+// BindBufferBase -
+func (o *TRecorder) BindBufferBase(target, index, id uint32) {
+	o.bound[target] = id
+	o.record("BindBufferBase", target, index, id)
+}
+
+// Uniform1i -
+func (o *TRecorder) Uniform1i(loc, v int32) { o.record("Uniform1i", loc, v) }
+
+// Uniform1f -
+func (o *TRecorder) Uniform1f(loc int32, v float32) { o.record("Uniform1f", loc, v) }
+
+// Uniform2f -
+func (o *TRecorder) Uniform2f(loc int32, v0, v1 float32) { o.record("Uniform2f", loc, v0, v1) }
+
+// Uniform3f -
+func (o *TRecorder) Uniform3f(loc int32, v0, v1, v2 float32) { o.record("Uniform3f", loc, v0, v1, v2) }
+
+// Uniform4f -
+func (o *TRecorder) Uniform4f(loc int32, v0, v1, v2, v3 float32) {
+	o.record("Uniform4f", loc, v0, v1, v2, v3)
+}
+
+// UniformMatrix4fv -
+func (o *TRecorder) UniformMatrix4fv(loc int32, transpose bool, m []float32) {
+	o.record("UniformMatrix4fv", loc, transpose, append([]float32(nil), m...))
+}
+
+// Uniform1fv -
+func (o *TRecorder) Uniform1fv(loc int32, v []float32) {
+	o.record("Uniform1fv", loc, append([]float32(nil), v...))
+}
+
+// Uniform2fv -
+func (o *TRecorder) Uniform2fv(loc int32, v []float32) {
+	o.record("Uniform2fv", loc, append([]float32(nil), v...))
+}
+
+// Uniform3fv -
+func (o *TRecorder) Uniform3fv(loc int32, v []float32) {
+	o.record("Uniform3fv", loc, append([]float32(nil), v...))
+}
+
+// Uniform4fv -
+func (o *TRecorder) Uniform4fv(loc int32, v []float32) {
+	o.record("Uniform4fv", loc, append([]float32(nil), v...))
+}
+
+// Uniform1iv -
+func (o *TRecorder) Uniform1iv(loc int32, v []int32) {
+	o.record("Uniform1iv", loc, append([]int32(nil), v...))
+}
+
+// Uniform1uiv -
+func (o *TRecorder) Uniform1uiv(loc int32, v []uint32) {
+	o.record("Uniform1uiv", loc, append([]uint32(nil), v...))
+}
+
+// UniformMatrix3fv -
+func (o *TRecorder) UniformMatrix3fv(loc int32, transpose bool, m []float32) {
+	o.record("UniformMatrix3fv", loc, transpose, append([]float32(nil), m...))
+}
@@ -0,0 +1,155 @@
+package ui
+
+import (
+	"testing"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+)
+
+// useRecorder makes a new recorder the device of the objects the test
+// creates.
+func useRecorder(t *testing.T) *TRecorder {
+	t.Helper()
+	rec := NewRecorder()
+	prev := SetDevice(rec)
+	t.Cleanup(func() { SetDevice(prev) })
+	return rec
+}
+
+func TestObjectsKeepTheirDevice(t *testing.T) {
+	a := useRecorder(t)
+	buf := NewArrayBuffer[float32]()
+	vao := NewVao()
+	b := NewRecorder()
+	SetDevice(b)
+
+	vao.Bind()
+	buf.Bind()
+	if cmds := b.Commands(); len(cmds) != 0 {
+		t.Errorf("the new device got %v", cmds)
+	}
+	got := a.Find("BindVertexArray", "BindBuffer")
+	if len(got) != 2 || got[0].Args[0] != vao.id || got[1].Args[1] != buf.id {
+		t.Errorf("the device of the objects got %v", got)
+	}
+}
+
+func TestRecorderDeclaresPerProgram(t *testing.T) {
+	rec := useRecorder(t)
+	rec.DeclareAttrib("Pos", 1, gl.FLOAT_VEC2)
+	rec.DeclareUniform("Scale", 1, gl.FLOAT)
+	first, err := NewProgram("vs", "fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.DeclareAttrib("Vertex", 1, gl.FLOAT_VEC4)
+	rec.DeclareUniformBlock("Frame", 16, TUniformMember{Name: "Tint", Type: gl.FLOAT_VEC4, Num: 1})
+	second, err := NewProgram("vs", "fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(params []TAttribParams) []string {
+		ret := []string{}
+		for _, p := range params {
+			ret = append(ret, p.name)
+		}
+		return ret
+	}
+	tests := []struct {
+		prog     *TProgram
+		attribs  []string
+		uniforms []string
+		blocks   int
+	}{
+		{first, []string{"Pos"}, []string{"Scale"}, 0},
+		{second, []string{"Vertex"}, []string{}, 1},
+	}
+	for i, tt := range tests {
+		if got := names(tt.prog.AttribParams()); !equalStrings(got, tt.attribs) {
+			t.Errorf("program %v: attributes %v, want %v", i, got, tt.attribs)
+		}
+		if got := names(tt.prog.UniformParams()); !equalStrings(got, tt.uniforms) {
+			t.Errorf("program %v: uniforms %v, want %v", i, got, tt.uniforms)
+		}
+		if got := len(tt.prog.UniformBlocks()); got != tt.blocks {
+			t.Errorf("program %v: %v uniform blocks, want %v", i, got, tt.blocks)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFramebufferResize(t *testing.T) {
+	rec := useRecorder(t)
+	fb, err := NewFramebuffer(8, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := fb.Texture().ID()
+	oldDepth := fb.depthStencil
+	fb.Bind()
+
+	rec.SetFramebufferStatus(gl.FRAMEBUFFER_UNSUPPORTED)
+	rec.Reset()
+	if err := fb.Resize(16, 8); err == nil {
+		t.Fatal("resized an incomplete framebuffer")
+	}
+	if w, h := fb.Size(); w != 8 || h != 4 {
+		t.Errorf("size %vx%v after a failed resize, want 8x4", w, h)
+	}
+	if fb.Texture().ID() != old || fb.depthStencil != oldDepth {
+		t.Errorf("attachments %v, %v after a failed resize, want %v, %v",
+			fb.Texture().ID(), fb.depthStencil, old, oldDepth)
+	}
+	attached := rec.Find("FramebufferTexture2D", "FramebufferRenderbuffer")
+	if n := len(attached); n != 4 || attached[2].Args[3] != old || attached[3].Args[3] != oldDepth {
+		t.Errorf("the old attachments are not attached again: %v", attached)
+	}
+	if got := rec.Find("DeleteTexture", "DeleteRenderbuffer"); len(got) != 2 ||
+		got[0].Args[0] == old || got[1].Args[0] == oldDepth {
+		t.Errorf("want the new attachments deleted, got %v", got)
+	}
+	if got := rec.Find("Viewport"); len(got) != 0 {
+		t.Errorf("the viewport changed: %v", got)
+	}
+
+	rec.SetFramebufferStatus(gl.FRAMEBUFFER_COMPLETE)
+	rec.Reset()
+	if err := fb.Resize(16, 8); err != nil {
+		t.Fatal(err)
+	}
+	if w, h := fb.Size(); w != 16 || h != 8 {
+		t.Errorf("size %vx%v, want 16x8", w, h)
+	}
+	if got := rec.Find("DeleteTexture", "DeleteRenderbuffer"); len(got) != 2 ||
+		got[0].Args[0] != old || got[1].Args[0] != oldDepth {
+		t.Errorf("want the old attachments deleted, got %v", got)
+	}
+	if got := rec.Find("Viewport"); len(got) != 1 || got[0].String() != "Viewport(0, 0, 16, 8)" {
+		t.Errorf("viewport of the bound framebuffer: %v", got)
+	}
+}
+
+func TestNewFramebufferIncomplete(t *testing.T) {
+	rec := useRecorder(t)
+	rec.SetFramebufferStatus(gl.FRAMEBUFFER_INCOMPLETE_ATTACHMENT)
+	if _, err := NewFramebuffer(8, 4); err == nil {
+		t.Fatal("created an incomplete framebuffer")
+	}
+	for _, name := range []string{"DeleteTexture", "DeleteRenderbuffer", "DeleteFramebuffer"} {
+		if len(rec.Find(name)) != 1 {
+			t.Errorf("want one %v, got %v", name, rec.Find(name))
+		}
+	}
+}
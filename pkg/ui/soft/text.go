@@ -0,0 +1,236 @@
+// Package soft draws text into images without GL. TText has the API of
+// ui.TText and produces the same pixels as it does on a framebuffer without
+// sRGB: the coverage is corrected with the text gamma and contrast of the
+// theme and blended over the image.
+package soft
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+
+	"github.com/macroblock/exp/pkg/ui/fontface"
+	"github.com/macroblock/exp/pkg/ui/theme"
+)
+
+// Identity - the transform that leaves glyphs where the layout put them.
+var Identity = f64.Aff3{1, 0, 0, 0, 1, 0}
+
+// TText - draws laid out glyphs into Dst.
+type TText struct {
+	Dst *image.RGBA
+
+	font      *fontface.TFontFace
+	color     [3]float32
+	clip      image.Rectangle
+	transform f64.Aff3
+
+	mask *image.Alpha
+}
+
+// NewText - creates a renderer with the face of the default theme.
+func NewText(dst *image.RGBA) (*TText, error) {
+	face, err := theme.Default.GetFontFace()
+	if err != nil {
+		return nil, err
+	}
+	return NewTextWithFace(dst, face), nil
+}
+
+// NewTextWithFace - creates a renderer with the face, e.g. one of
+// fontface.Go. The text is black until SetTextColor.
+func NewTextWithFace(dst *image.RGBA, face *fontface.TFontFace) *TText {
+	return &TText{
+		Dst:       dst,
+		font:      face,
+		transform: Identity,
+	}
+}
+
+// Font -
+func (o *TText) Font() *fontface.TFontFace {
+	return o.font
+}
+
+// SetFont -
+func (o *TText) SetFont(face *fontface.TFontFace) {
+	o.font = face
+}
+
+// SetTextColor - the alpha is ignored like ui.TText does.
+func (o *TText) SetTextColor(r, g, b, a float32) {
+	o.color = [3]float32{r, g, b}
+}
+
+// SetClip - limits the drawing to r, an empty rectangle removes the limit.
+func (o *TText) SetClip(r image.Rectangle) {
+	o.clip = r
+}
+
+// Clip -
+func (o *TText) Clip() image.Rectangle {
+	return o.clip
+}
+
+// SetTransform - maps the laid out glyphs to Dst. Glyphs moved by whole
+// pixels are copied from the atlas as they are, other transforms resample
+// them bilinearly and draw LCD faces in grayscale, since the color stripes
+// do not survive scaling or rotation.
+func (o *TText) SetTransform(m f64.Aff3) {
+	o.transform = m
+}
+
+// Transform -
+func (o *TText) Transform() f64.Aff3 {
+	return o.transform
+}
+
+// RenderText -
+func (o *TText) RenderText(s string, x0, y0 int, screenW, screenH int) {
+	o.RenderSpans([]fontface.TSpan{{Text: s}}, x0, y0, 0, screenW, screenH)
+}
+
+// RenderSpans - draws colored spans wrapped at maxWidth, see TFontFace.Layout.
+// Spans without a color use the one of SetTextColor.
+func (o *TText) RenderSpans(spans []fontface.TSpan, x0, y0, maxWidth int, screenW, screenH int) {
+	o.RenderSpansAt(spans, float32(x0), y0, maxWidth, screenW, screenH)
+}
+
+// RenderSpansAt - same as RenderSpans but x0 may be fractional. Nothing is
+// drawn outside of screenW x screenH at the origin of Dst.
+func (o *TText) RenderSpansAt(spans []fontface.TSpan, x0 float32, y0, maxWidth int, screenW, screenH int) {
+	o.RenderGlyphs(o.font.LayoutAt(spans, x0, maxWidth), y0, screenW, screenH)
+}
+
+// RenderGlyphs - draws glyphs laid out with the face of the renderer.
+func (o *TText) RenderGlyphs(glyphs []fontface.TGlyph, y0 int, screenW, screenH int) {
+	b := o.Dst.Bounds()
+	clip := b.Intersect(image.Rect(b.Min.X, b.Min.Y, b.Min.X+screenW, b.Min.Y+screenH))
+	if !o.clip.Empty() {
+		clip = clip.Intersect(o.clip)
+	}
+	if clip.Empty() {
+		return
+	}
+	dst := o.Dst.SubImage(clip).(*image.RGBA)
+
+	gamma := theme.Default.GetTextGamma()
+	contrast := theme.Default.GetTextContrast()
+	dx, dy, shift := o.shift()
+	lutColor, lut := [3]float32{-1}, [256]uint8{}
+	for _, glyph := range glyphs {
+		ch := glyph.Char
+		if ch.Rect.Empty() {
+			continue
+		}
+		c := o.color
+		if glyph.Color != nil {
+			c = colorToFloat32(glyph.Color)
+		}
+		if c != lutColor {
+			lutColor, lut = c, coverageLUT(c, gamma, contrast)
+		}
+		pos := image.Pt(glyph.X+ch.Offset.X, y0+glyph.Y+ch.Offset.Y)
+
+		if o.font.TexLCD != nil && shift {
+			o.drawLCD(dst, pos.Add(image.Pt(dx, dy)), ch.Rect, c, &lut)
+			continue
+		}
+		mask := o.coverage(ch.Rect, &lut)
+		src := image.NewUniform(color.RGBA{to8(c[0]), to8(c[1]), to8(c[2]), 0xff})
+		if shift {
+			r := image.Rectangle{pos, pos.Add(ch.Rect.Size())}.Add(image.Pt(dx, dy))
+			draw.DrawMask(dst, r, src, image.Point{}, mask, image.Point{}, draw.Over)
+			continue
+		}
+		m := o.transform
+		s2d := f64.Aff3{
+			m[0], m[1], m[0]*float64(pos.X) + m[1]*float64(pos.Y) + m[2],
+			m[3], m[4], m[3]*float64(pos.X) + m[4]*float64(pos.Y) + m[5],
+		}
+		xdraw.BiLinear.Transform(dst, s2d, src, mask.Bounds(), draw.Over, &xdraw.Options{
+			SrcMask: mask,
+		})
+	}
+}
+
+// shift reports whether the transform moves by whole pixels only and by
+// how much.
+func (o *TText) shift() (int, int, bool) {
+	m := o.transform
+	if m[0] != 1 || m[1] != 0 || m[3] != 0 || m[4] != 1 ||
+		m[2] != math.Trunc(m[2]) || m[5] != math.Trunc(m[5]) {
+		return 0, 0, false
+	}
+	return int(m[2]), int(m[5]), true
+}
+
+// coverage returns the corrected coverage of the atlas rect at the origin.
+// LCD faces use the maximum of the stripes the atlas holds in Tex.
+func (o *TText) coverage(rect image.Rectangle, lut *[256]uint8) *image.Alpha {
+	w, h := rect.Dx(), rect.Dy()
+	if o.mask == nil || o.mask.Rect.Dx() < w || o.mask.Rect.Dy() < h {
+		o.mask = image.NewAlpha(image.Rect(0, 0, w*2, h*2))
+	}
+	mask := o.mask.SubImage(image.Rect(0, 0, w, h)).(*image.Alpha)
+	tex := o.font.Tex
+	for y := 0; y < h; y++ {
+		src := tex.Pix[tex.PixOffset(rect.Min.X, rect.Min.Y+y):]
+		dst := mask.Pix[y*mask.Stride:]
+		for x := 0; x < w; x++ {
+			dst[x] = lut[src[x]]
+		}
+	}
+	return mask
+}
+
+// drawLCD blends every channel by the coverage of its stripe the way the
+// two passes of ui.TText do.
+func (o *TText) drawLCD(dst *image.RGBA, pos image.Point, rect image.Rectangle, c [3]float32, lut *[256]uint8) {
+	r := image.Rectangle{pos, pos.Add(rect.Size())}.Intersect(dst.Rect)
+	if r.Empty() {
+		return
+	}
+	sp := rect.Min.Add(r.Min.Sub(pos))
+	col := [3]uint32{uint32(to8(c[0])), uint32(to8(c[1])), uint32(to8(c[2]))}
+	tex := o.font.TexLCD
+	for y := 0; y < r.Dy(); y++ {
+		src := tex.Pix[tex.PixOffset(sp.X, sp.Y+y):]
+		d := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y+y):]
+		for x := 0; x < r.Dx(); x++ {
+			for i := 0; i < 3; i++ {
+				a := uint32(lut[src[x*4+i]])
+				v := (uint32(d[x*4+i])*(255-a) + col[i]*a + 127) / 255
+				d[x*4+i] = uint8(v)
+			}
+			d[x*4+3] = 0xff
+		}
+	}
+}
+
+// coverageLUT maps the coverage of the atlas to alpha the way the shader of
+// ui.TText does, see theme.TextGamma and theme.TextContrast.
+func coverageLUT(c [3]float32, gamma, contrast float64) [256]uint8 {
+	luma := 0.2126*float64(c[0]) + 0.7152*float64(c[1]) + 0.0722*float64(c[2])
+	exp := gamma + (1/gamma-gamma)*luma
+	ret := [256]uint8{}
+	for i := range ret {
+		a := (float64(i)/255-0.5)*(1+contrast) + 0.5
+		a = math.Min(math.Max(a, 0), 1)
+		ret[i] = uint8(math.Pow(a, exp)*255 + 0.5)
+	}
+	return ret
+}
+
+func colorToFloat32(c color.Color) [3]float32 {
+	r, g, b, _ := c.RGBA()
+	return [3]float32{float32(r) / 0xffff, float32(g) / 0xffff, float32(b) / 0xffff}
+}
+
+func to8(v float32) uint8 {
+	return uint8(math.Min(math.Max(float64(v), 0), 1)*255 + 0.5)
+}
@@ -0,0 +1,110 @@
+package soft_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/f64"
+
+	"github.com/macroblock/exp/pkg/ui"
+	"github.com/macroblock/exp/pkg/ui/fontface"
+	"github.com/macroblock/exp/pkg/ui/golden"
+	"github.com/macroblock/exp/pkg/ui/soft"
+	"github.com/macroblock/exp/pkg/ui/theme"
+)
+
+const testW, testH = 200, 48
+
+var testSpans = []fontface.TSpan{
+	{Text: "Hello, "},
+	{Text: "soft", Color: color.RGBA{0xff, 0x80, 0x20, 0xff}},
+	{Text: " text"},
+}
+
+func testFace(t *testing.T, lcd fontface.TLCDOrder) *fontface.TFontFace {
+	t.Helper()
+	face, err := fontface.NewFromReader(bytes.NewReader(goregular.TTF), 16, ' ', '~'+1,
+		fontface.WithLCD(lcd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return face
+}
+
+// render draws the spans the way the GL tests do: white text over the
+// dark color of the theme.
+func render(face *fontface.TFontFace, transform f64.Aff3) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, testW, testH))
+	bg := image.NewUniform(theme.Default.GetPalette()[theme.Dark])
+	draw.Draw(img, img.Bounds(), bg, image.Point{}, draw.Src)
+	text := soft.NewTextWithFace(img, face)
+	text.SetTextColor(1, 1, 1, 1)
+	text.SetTransform(transform)
+	text.RenderSpans(testSpans, 4, 4, 0, testW, testH)
+	return img
+}
+
+func TestTextGray(t *testing.T) {
+	img := render(testFace(t, fontface.LCDNone), soft.Identity)
+	golden.Assert(t, "gray", img, nil)
+}
+
+func TestTextLCD(t *testing.T) {
+	gray := render(testFace(t, fontface.LCDNone), soft.Identity)
+	lcd := render(testFace(t, fontface.LCDRGB), soft.Identity)
+	golden.Assert(t, "lcd", lcd, nil)
+	if res, _, _ := golden.Compare(lcd, gray, golden.TOptions{}); res.DiffPixels == 0 {
+		t.Error("LCD text is drawn in grayscale")
+	}
+}
+
+func TestTextTransform(t *testing.T) {
+	face := testFace(t, fontface.LCDNone)
+	a := 0.2
+	rotated := f64.Aff3{math.Cos(a), -math.Sin(a), 10.5, math.Sin(a), math.Cos(a), 0.25}
+	img := render(face, rotated)
+	golden.Assert(t, "transform", img, nil)
+
+	// a shift by whole pixels copies the glyphs
+	moved := render(face, f64.Aff3{1, 0, 3, 0, 1, 2})
+	plain := render(face, soft.Identity)
+	shifted := image.NewRGBA(plain.Bounds())
+	draw.Draw(shifted, shifted.Bounds(), plain, image.Pt(-3, -2), draw.Src)
+	if res, _, _ := golden.Compare(moved.SubImage(image.Rect(3, 2, testW, testH)),
+		shifted.SubImage(image.Rect(3, 2, testW, testH)), golden.TOptions{}); res.DiffPixels != 0 {
+		t.Errorf("shift by 3,2: %v", res)
+	}
+}
+
+// TestTextMatchesGL draws the same frames with ui.TText, the test is
+// skipped without a GL driver.
+func TestTextMatchesGL(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		lcd  fontface.TLCDOrder
+	}{
+		{"gray", fontface.LCDNone},
+		{"lcd", fontface.LCDRGB},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			face := testFace(t, tt.lcd)
+			got := golden.Render(t, testW, testH, func(ctx *ui.TContext) {
+				text := ui.NewTextWithFace(face)
+				text.SetTextColor(1, 1, 1, 1)
+				text.RenderSpans(testSpans, 4, 4, 0, testW, testH)
+			})
+			res, _, err := golden.Compare(got, render(face, soft.Identity), golden.DefaultOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Ratio() > golden.DefaultOptions.MaxDiffRatio {
+				t.Errorf("soft and GL text differ: %v", res)
+			}
+		})
+	}
+}
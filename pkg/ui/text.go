@@ -1,52 +1,66 @@
 package ui
 
 import (
-	"bytes"
-	"fmt"
-	"unsafe"
-
-	"golang.org/x/image/font/gofont/goregular"
+	"image"
 
 	"github.com/go-gl/mathgl/mgl32"
 	"github.com/macroblock/exp/pkg/ui/fontface"
+	"github.com/macroblock/exp/pkg/ui/theme"
 
 	gl "github.com/go-gl/gl/v3.1/gles2"
 )
 
+// shader modes of TText
+const (
+	textModeGray = int32(iota)
+	textModeLCDCoverage
+	textModeLCDColor
+)
+
 type (
+	// tTextVertex - a corner of a glyph quad.
+	tTextVertex struct {
+		XYST [4]float32 `gl:"Vertex,loc=0"`
+	}
+
 	// IMesh -
 	// IMesh interface {
 	// 	Data() []TBuffer
 	// }
 
-	// TTexture -
-	TTexture struct {
-		id  uint32
-		typ string
-	}
-
 	// TText -
 	TText struct {
-		vertices  []float32
-		indices   []uint32
-		textures  []TTexture
-		vao       *TVertexArrayObject
-		stride    int32
-		vbo       *TArrayBuffer
-		ebo       *TElementArrayBuffer
-		prog      *TProgram
-		font      *fontface.TFontFace
-		texHandle uint32
-
-		winW int
-		winH int
+		dev      *tDevice
+		vertices []float32
+		indices  []uint32
+		vao      *TVertexArrayObject
+		stride   int32
+		vbo      *TArrayBuffer[tTextVertex]
+		ebo      *TElementArrayBuffer[uint32]
+		prog     *TProgram
+		font     *fontface.TFontFace
+		tex      *TTexture
+
+		winW  int
+		winH  int
+		color [3]float32
 
 		orthoMatrix mgl32.Mat4
 	}
 )
 
-// NewText -
+// NewText - creates a text renderer with the face of the default theme.
 func NewText() *TText {
+	face, err := theme.Default.GetFontFace()
+	if err != nil {
+		logPanicf("%v", err)
+	}
+	return NewTextWithFace(face)
+}
+
+// NewTextWithFace - creates a text renderer with the face, e.g. one of
+// fontface.Go.
+func NewTextWithFace(face *fontface.TFontFace) *TText {
 	// vShader := `#version 300 es
 	//     #extension GL_ARB_explicit_uniform_location : enable
 	//     layout(location=0) in vec2 aPosition;
@@ -74,11 +88,10 @@ func NewText() *TText {
 	//     }
 	// ` + "\x00"
 	vShader := `#version 300 es
-        #extension GL_ARB_explicit_uniform_location : enable
         layout(location=0) in vec4 Vertex; // [xy, st]
 
-        layout(location=3) uniform mat4 Ortho;
-        layout(location=4) uniform vec3 inColor;
+        uniform mat4 Ortho;
+        uniform vec3 inColor;
 
         out vec2 TexCoords;
         out vec3 Color;
@@ -96,16 +109,36 @@ func NewText() *TText {
         in vec2 TexCoords;
         out vec4 outColor;
         uniform sampler2D texSampler;
+        uniform int Mode;
+        uniform float Gamma;
+        uniform float Contrast;
+
+        // coverage as alpha, corrected so the weight of the text does not
+        // depend on its color
+        vec3 alpha(vec3 coverage) {
+            vec3 a = clamp((coverage-0.5)*(1.0+Contrast)+0.5, 0.0, 1.0);
+            float luma = dot(Color, vec3(0.2126, 0.7152, 0.0722));
+            return pow(a, vec3(mix(Gamma, 1.0/Gamma, luma)));
+        }
+
         void main() {
-            vec4 sampled = vec4(Color.rgb, texture(texSampler,TexCoords).r);
-            outColor = sampled ;
+            vec4 texel = texture(texSampler,TexCoords);
+            if (Mode == 1) {
+                // LCD: darken the background by the coverage of every stripe
+                outColor = vec4(alpha(texel.rgb), 1.0);
+            } else if (Mode == 2) {
+                // LCD: add the color weighted the same way
+                outColor = vec4(Color*alpha(texel.rgb), 1.0);
+            } else {
+                outColor = vec4(Color.rgb, alpha(texel.rrr).r);
+            }
         }
     ` + "\x00"
 	program, err := NewProgram(vShader, fShader)
 	if err != nil {
 		logPanicf("%v", err)
 	}
-	ret := &TText{}
+	ret := &TText{dev: program.dev}
 	// l := float32(1.0)
 	// z := float32(0.5)
 	// u0 := float32(0.)
@@ -120,12 +153,7 @@ func NewText() *TText {
 	// ret.indices = []uint32{0, 1, 2}
 	// ret.stride = 3
 	ret.prog = program
-
-	ret.font, err = fontface.NewFromReader(bytes.NewReader(goregular.TTF), 14, '{', 'z')
-	// ret.font, err = loadTTF(bytes.NewReader(gomono.TTF), 24, '{', 'z')
-	if err != nil {
-		fmt.Printf("\n Font: %v", err)
-	}
+	ret.font = face
 
 	ret.Setup()
 	return ret
@@ -140,44 +168,28 @@ func (o *TText) Setup() {
 	}
 	_ = bmp
 	o.prog.Use()
-	vao := NewVao()
-	vbo := NewArrayBuffer()
-	ebo := NewElementArrayBuffer()
+	vao := newVao(o.dev)
+	vbo := newArrayBuffer[tTextVertex](o.dev)
+	ebo := newElementArrayBuffer[uint32](o.dev)
 	o.vao = vao
 	o.vbo = vbo
 	o.ebo = ebo
-	gl.GenTextures(1, &o.texHandle)
 
 	vao.Bind()
 
-	// p := o.font.glyphMap['▓']
-	p := o.font.Tex
-	b := p.Bounds()
-	_, _ = p, b
-	gl.BindTexture(gl.TEXTURE_2D, o.texHandle)
-
-	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
-
-	gl.TexImage2D(gl.TEXTURE_2D, 0,
-		gl.RGB, // gl.LUMINANCE, // gl.RGB,
-		int32(b.Dx()), int32(b.Dy()),
-		0, gl.LUMINANCE, gl.UNSIGNED_BYTE, unsafe.Pointer(&p.Pix[0]))
-	// gl.TexImage2D(gl.TEXTURE_2D, 0,
-	// 	gl.RGB,
-	// 	int32(2), int32(2),
-	// 	0, gl.RED, gl.UNSIGNED_BYTE, unsafe.Pointer(&bmp[0]))
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
-
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_BORDER_NV)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_BORDER_NV)
-	// gl.TexParameterfv(gl.TEXTURE_2D, gl.TEXTURE_BORDER_COLOR_NV, &[]float32{1, 1, 0, 1}[0])
+	o.uploadFont()
 
 	vbo.Bind()
-	vbo.Data(make([]float32, 4*6, 4*6), gl.DYNAMIC_DRAW)
-
-	gl.EnableVertexAttribArray(0)
-	gl.VertexAttribPointer(0, 4, gl.FLOAT, false, 4*4, nil)
+	if err := vbo.Data(make([]tTextVertex, 6), gl.DYNAMIC_DRAW); err != nil {
+		logPanicf("%v", err)
+	}
+	layout, err := NewVertexLayout[tTextVertex]()
+	if err != nil {
+		logPanicf("%v", err)
+	}
+	if err := vao.ApplyLayout(vbo, layout, o.prog); err != nil {
+		logPanicf("%v", err)
+	}
 
 	// ebo.Bind()
 	// ebo.Data(o.indices, gl.STATIC_DRAW)
@@ -189,6 +201,33 @@ func (o *TText) Setup() {
 	vao.Unbind()
 }
 
+func (o *TText) uploadFont() {
+	var img image.Image = o.font.Tex
+	if o.font.TexLCD != nil {
+		img = o.font.TexLCD
+	}
+	tex, err := newTexture(o.dev, img)
+	if err != nil {
+		logPanicf("%v", err)
+	}
+	tex.SetFilter(gl.NEAREST, gl.NEAREST)
+	if o.tex != nil {
+		o.tex.Delete()
+	}
+	o.tex = tex
+}
+
+// Font -
+func (o *TText) Font() *fontface.TFontFace {
+	return o.font
+}
+
+// SetFont - replaces the face and uploads its atlas.
+func (o *TText) SetFont(face *fontface.TFontFace) {
+	o.font = face
+	o.uploadFont()
+}
+
 // Draw -
 func (o *TText) Draw() {
 	// o.prog.Use()
@@ -210,21 +249,37 @@ func (o *TText) ResizeWindow(w int, h int) {
 // SetTextColor -
 func (o *TText) SetTextColor(r, g, b, a float32) {
 	o.prog.Use()
-	gl.Uniform3f(4, r, g, b)
+	o.color = [3]float32{srgbToLinear(r), srgbToLinear(g), srgbToLinear(b)}
+	setUniform(o.prog, "inColor", mgl32.Vec3(o.color))
 }
 
 // RenderText -
 func (o *TText) RenderText(s string, x0, y0 int, screenW, screenH int) {
-	gl.Disable(gl.DEPTH_TEST)
+	o.RenderSpans([]fontface.TSpan{{Text: s}}, x0, y0, 0, screenW, screenH)
+}
+
+// RenderSpans - draws colored spans wrapped at maxWidth, see TFontFace.Layout.
+// Spans without a color use the one of SetTextColor.
+func (o *TText) RenderSpans(spans []fontface.TSpan, x0, y0, maxWidth int, screenW, screenH int) {
+	o.RenderSpansAt(spans, float32(x0), y0, maxWidth, screenW, screenH)
+}
 
-	gl.Enable(gl.BLEND)
-	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+// RenderSpansAt - same as RenderSpans but x0 may be fractional. Faces with
+// subpixel phases keep the fraction, others snap to whole pixels. LCD faces
+// are blended per color channel in two passes since GLES has no dual source
+// blending: the first one scales the background by one minus the coverage,
+// the second one adds the color times the coverage.
+func (o *TText) RenderSpansAt(spans []fontface.TSpan, x0 float32, y0, maxWidth int, screenW, screenH int) {
+	o.dev.Disable(gl.DEPTH_TEST)
+
+	o.dev.Enable(gl.BLEND)
+	o.dev.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
 
 	o.prog.Use()
 
 	mtx := mgl32.Ortho(float32(0), float32(screenW), float32(screenH), float32(0), -1.0, 1.0)
 	mtx = mgl32.Ortho2D(float32(0), float32(screenW), float32(screenH), float32(0))
-	gl.UniformMatrix4fv(3, 1, false, &mtx[0])
+	setUniform(o.prog, "Ortho", mtx)
 
 	// cr := float32(0.5+255) / 256
 	// cg := float32(0.5+128) / 256
@@ -232,17 +287,30 @@ func (o *TText) RenderText(s string, x0, y0 int, screenW, screenH int) {
 	// cr = 1
 	// cg = 1
 	// cb = 1
-	// gl.Uniform3f(4, cr, cg, cb)
+	// setUniform(o.prog, "inColor", mgl32.Vec3{cr, cg, cb})
+
+	lcd := o.font.TexLCD != nil
+	setUniform(o.prog, "Mode", textModeGray)
+	setUniform(o.prog, "Gamma", float32(theme.Default.GetTextGamma()))
+	setUniform(o.prog, "Contrast", float32(theme.Default.GetTextContrast()))
 
 	scale := float32(1.0 / 1)
-	gl.BindBuffer(gl.TEXTURE_2D, o.texHandle)
+	o.tex.Bind(0)
+	setUniform(o.prog, "texSampler", 0)
 	o.vao.Bind()
-	x := float32(x0)
-	y := float32(y0)
-	for _, r := range s {
-		ch, ok := o.font.CharMap[r]
-		if !ok {
-			continue
+	colored := false
+	for _, glyph := range o.font.LayoutAt(spans, x0, maxWidth) {
+		ch := glyph.Char
+		x := float32(glyph.X)
+		y := float32(y0 + glyph.Y)
+		switch {
+		case glyph.Color != nil:
+			r, g, b, _ := shaderColor(glyph.Color)
+			setUniform(o.prog, "inColor", mgl32.Vec3{r, g, b})
+			colored = true
+		case colored:
+			setUniform(o.prog, "inColor", mgl32.Vec3(o.color))
+			colored = false
 		}
 
 		// fmt.Printf("%q xy %v %v wh %vx%v adv %v | ", r, ch.texX, ch.texY, ch.W, ch.H, ch.advanceX)
@@ -262,20 +330,38 @@ func (o *TText) RenderText(s string, x0, y0 int, screenW, screenH int) {
 		// t = 0
 		// texW = 1
 		// texH = 1
-		vertices := []float32{
-			xpos0, ypos1, s0, t0,
-			xpos0, ypos0, s0, t1,
-			xpos1, ypos0, s1, t1,
-
-			xpos0, ypos1, s0, t0,
-			xpos1, ypos0, s1, t1,
-			xpos1, ypos1, s1, t0,
+		vertices := []tTextVertex{
+			{[4]float32{xpos0, ypos1, s0, t0}},
+			{[4]float32{xpos0, ypos0, s0, t1}},
+			{[4]float32{xpos1, ypos0, s1, t1}},
+
+			{[4]float32{xpos0, ypos1, s0, t0}},
+			{[4]float32{xpos1, ypos0, s1, t1}},
+			{[4]float32{xpos1, ypos1, s1, t0}},
 		}
-		gl.BindBuffer(gl.ARRAY_BUFFER, o.vbo.id)
-		gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(vertices)*4, unsafe.Pointer(&vertices[0]))
-		gl.DrawArrays(gl.TRIANGLES, 0, 6)
-		x += float32(ch.Advance.X) * scale
+		o.vbo.Bind()
+		if err := o.vbo.SubData(0, vertices); err != nil {
+			logPanicf("%v", err)
+		}
+		if !lcd {
+			o.dev.DrawArrays(gl.TRIANGLES, 0, 6)
+			continue
+		}
+		setUniform(o.prog, "Mode", textModeLCDCoverage)
+		o.dev.BlendFunc(gl.ZERO, gl.ONE_MINUS_SRC_COLOR)
+		o.dev.DrawArrays(gl.TRIANGLES, 0, 6)
+		setUniform(o.prog, "Mode", textModeLCDColor)
+		o.dev.BlendFunc(gl.ONE, gl.ONE)
+		o.dev.DrawArrays(gl.TRIANGLES, 0, 6)
 		// break
 	}
+	if colored {
+		setUniform(o.prog, "inColor", mgl32.Vec3(o.color))
+	}
+	if lcd {
+		setUniform(o.prog, "Mode", textModeGray)
+		o.dev.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	}
 	o.vao.Unbind()
+	o.tex.Unbind(0)
 }
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+	"golang.org/x/image/font/gofont/goregular"
+
+	"github.com/macroblock/exp/pkg/ui/fontface"
+)
+
+func testFace(t *testing.T, lcd fontface.TLCDOrder) *fontface.TFontFace {
+	t.Helper()
+	face, err := fontface.NewFromReader(bytes.NewReader(goregular.TTF), 16, ' ', '~'+1,
+		fontface.WithLCD(lcd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return face
+}
+
+// declareText declares the variables of the program of TText.
+func declareText(rec *TRecorder) {
+	rec.DeclareAttrib("Vertex", 1, gl.FLOAT_VEC4)
+	rec.DeclareUniform("Ortho", 1, gl.FLOAT_MAT4)
+	rec.DeclareUniform("inColor", 1, gl.FLOAT_VEC3)
+	rec.DeclareUniform("texSampler", 1, gl.SAMPLER_2D)
+	rec.DeclareUniform("Mode", 1, gl.INT)
+	rec.DeclareUniform("Gamma", 1, gl.FLOAT)
+	rec.DeclareUniform("Contrast", 1, gl.FLOAT)
+}
+
+// declareTextMesh declares the variables of the program of TTextMesh.
+func declareTextMesh(rec *TRecorder) {
+	rec.DeclareAttrib("Vertex", 1, gl.FLOAT_VEC4)
+	rec.DeclareUniform("Ortho", 1, gl.FLOAT_MAT4)
+	rec.DeclareUniform("Offset", 1, gl.FLOAT_VEC2)
+	rec.DeclareUniform("inColor", 1, gl.FLOAT_VEC4)
+	rec.DeclareUniform("Edge", 1, gl.BOOL)
+}
+
+func TestTextCommands(t *testing.T) {
+	tests := []struct {
+		name   string
+		lcd    fontface.TLCDOrder
+		passes int
+		blends []string
+	}{
+		{"gray", fontface.LCDNone, 1, []string{"BlendFunc(770, 771)"}},
+		{"lcd", fontface.LCDRGB, 2, []string{"BlendFunc(770, 771)",
+			"BlendFunc(0, 769)", "BlendFunc(1, 1)", "BlendFunc(0, 769)", "BlendFunc(1, 1)",
+			"BlendFunc(770, 771)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := useRecorder(t)
+			declareText(rec)
+			text := NewTextWithFace(testFace(t, tt.lcd))
+
+			rec.Reset()
+			text.SetTextColor(1, 1, 1, 1)
+			text.RenderText("ab", 4, 2, 100, 50)
+
+			if got := rec.Find("DrawArrays"); len(got) != 2*tt.passes {
+				t.Errorf("%v draws, want %v", len(got), 2*tt.passes)
+			}
+			blends := []string{}
+			for _, c := range rec.Find("BlendFunc") {
+				blends = append(blends, c.String())
+			}
+			if !equalStrings(blends, tt.blends) {
+				t.Errorf("blending %v, want %v", blends, tt.blends)
+			}
+			ortho, err := text.prog.Uniform("Ortho")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := rec.Find("UniformMatrix4fv")
+			if len(got) != 1 || got[0].Args[0] != int32(ortho.index) {
+				t.Errorf("want Ortho set once at %v, got %v", ortho.index, got)
+			}
+			// 6 vertices of 4 floats per glyph
+			for _, c := range rec.Find("BufferSubData") {
+				if size := c.Args[2]; size != 6*4*4 {
+					t.Errorf("uploaded %v bytes, want a quad", size)
+				}
+			}
+		})
+	}
+}
+
+func TestTextMeshCommands(t *testing.T) {
+	rec := useRecorder(t)
+	face := testFace(t, fontface.LCDNone)
+	declareText(rec)
+	text := NewTextWithFace(face)
+	declareTextMesh(rec)
+	mesh, err := NewTextMesh(face, "Hi", 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := text.prog.Uniform("Offset"); err == nil {
+		t.Error("the program of the text has the uniforms of the mesh")
+	}
+
+	rec.Reset()
+	mesh.Draw(10, 20, 100, 50, 1, 1, 1, 1)
+	text.RenderText("H", 0, 0, 100, 50)
+
+	uses := rec.Find("UseProgram")
+	if len(uses) == 0 || uses[0].Args[0] != mesh.prog.id || uses[len(uses)-1].Args[0] != text.prog.id {
+		t.Errorf("programs in use %v, want %v then %v", uses, mesh.prog.id, text.prog.id)
+	}
+	draws := rec.Find("DrawElements")
+	if len(draws) != 3 {
+		t.Fatalf("want the fill, the edge and the cover pass, got %v", draws)
+	}
+	if draws[0].Args[1] != mesh.nFill || mesh.nEdge == 0 ||
+		draws[1].Args[1] != mesh.nEdge || draws[1].Args[3] != int(mesh.nFill+6)*4 ||
+		draws[2].Args[1] != int32(6) || draws[2].Args[3] != int(mesh.nFill)*4 {
+		t.Errorf("passes %v, want %v fill and %v edge indices then the cover quad",
+			draws, mesh.nFill, mesh.nEdge)
+	}
+	// nonzero winding
+	ops := []string{}
+	for _, c := range rec.Find("StencilOpSeparate") {
+		ops = append(ops, c.String())
+	}
+	wantOps := []string{
+		fmt.Sprintf("StencilOpSeparate(%v, %v, %v, %v)", gl.FRONT, gl.KEEP, gl.KEEP, gl.INCR_WRAP),
+		fmt.Sprintf("StencilOpSeparate(%v, %v, %v, %v)", gl.BACK, gl.KEEP, gl.KEEP, gl.DECR_WRAP),
+	}
+	if !equalStrings(ops, wantOps) {
+		t.Errorf("stencil ops %v, want %v", ops, wantOps)
+	}
+	offset, err := mesh.prog.Uniform("Offset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("Uniform2fv(%v, [10 20])", offset.index)
+	if got := rec.Find("Uniform2fv"); len(got) != 1 || got[0].String() != want {
+		t.Errorf("offset %v, want %v", got, want)
+	}
+	if len(rec.Find("DrawArrays")) != 1 {
+		t.Errorf("the text is not drawn after the mesh: %v", rec.Find("DrawArrays"))
+	}
+}
@@ -0,0 +1,334 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/macroblock/exp/pkg/ui/fontface"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+)
+
+// uv of the fan triangles, u*u-v is always negative there
+const solidU, solidV = 0.0, 1.0
+
+// fringe - how far in pixels the edge quads reach out of the straight
+// edges, the cover quad is grown by as much to clear their stencil.
+const fringe = 1.0
+
+type (
+	// tMeshVertex - the location of Vertex is left to the shader compiler.
+	tMeshVertex struct {
+		XYUV [4]float32 `gl:"Vertex"`
+	}
+
+	// TTextMesh - resolution independent text made of glyph outlines. The
+	// contours are drawn as triangle fans plus Loop-Blinn curve triangles
+	// into the stencil buffer, front faces increment and back faces
+	// decrement it, so the fill follows the nonzero winding rule of
+	// TrueType and CFF. Edges are antialiased with derivatives: an edge
+	// pass draws the curve triangles again and a thin quad along every
+	// straight edge, and the fragment shader turns u*u-v and its screen
+	// space gradient (dFdx, dFdy) into the distance to the edge and the
+	// coverage of the pixel. The pass only blends outside of the fill, so
+	// the edges get a half pixel fringe on the outer side. Then a cover quad
+	// is filled where the winding number is not zero.
+	TTextMesh struct {
+		dev      *tDevice
+		vertices []tMeshVertex
+		indices  []uint32
+		bounds   [4]float32 // minX, minY, maxX, maxY
+		nFill    int32      // number of indices of the fill pass, the cover quad follows
+		nEdge    int32      // number of indices of the edge pass, after the cover quad
+		edges    []uint32
+		vao      *TVertexArrayObject
+		vbo      *TArrayBuffer[tMeshVertex]
+		ebo      *TElementArrayBuffer[uint32]
+		prog     *TProgram
+	}
+)
+
+// NewTextMesh - lays out s on a single line at size pixels per em with the
+// baseline at y = 0 and tessellates it.
+func NewTextMesh(font *fontface.TFontFace, s string, size float32) (*TTextMesh, error) {
+	if !font.HasOutlines() {
+		return nil, fmt.Errorf("font face has no outlines")
+	}
+	vShader := `#version 300 es
+        in vec4 Vertex; // [xy, uv]
+        uniform mat4 Ortho;
+        uniform vec2 Offset;
+        out vec2 UV;
+        void main() {
+            gl_Position = Ortho * vec4(Vertex.xy+Offset,0.0,1.0);
+            UV = Vertex.zw;
+        }
+    ` + "\x00"
+	fShader := `#version 300 es
+        precision highp float;
+        in vec2 UV;
+        uniform vec4 inColor;
+        uniform bool Edge;
+        out vec4 outColor;
+        void main() {
+            float f = UV.x*UV.x - UV.y;
+            if (!Edge) {
+                // outside of the quadratic curve
+                if (f > 0.0) {
+                    discard;
+                }
+                outColor = inColor;
+                return;
+            }
+            // distance to the curve in pixels by the gradient of f
+            vec2 du = vec2(dFdx(UV.x), dFdy(UV.x));
+            vec2 dv = vec2(dFdx(UV.y), dFdy(UV.y));
+            vec2 grad = 2.0*UV.x*du - dv;
+            float coverage = clamp(0.5 - abs(f)/max(length(grad), 1e-6), 0.0, 1.0);
+            if (coverage <= 0.0) {
+                discard;
+            }
+            outColor = vec4(inColor.rgb, inColor.a*coverage);
+        }
+    ` + "\x00"
+	program, err := NewProgram(vShader, fShader)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &TTextMesh{dev: program.dev, prog: program}
+	ret.bounds = [4]float32{math.MaxFloat32, math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32}
+	x := float32(0)
+	prev := rune(-1)
+	for _, r := range s {
+		if prev >= 0 {
+			x += font.Kern(prev, r, size)
+		}
+		prev = r
+		outline, err := font.Outline(r, size)
+		if err != nil {
+			continue
+		}
+		ret.addOutline(outline, x)
+		adv, err := font.GlyphAdvance(r, size)
+		if err != nil {
+			return nil, err
+		}
+		x += adv
+	}
+	ret.nFill = int32(len(ret.indices))
+	if ret.nFill == 0 {
+		ret.bounds = [4]float32{}
+	}
+	b := ret.bounds
+	base := uint32(len(ret.vertices))
+	ret.vertices = append(ret.vertices,
+		tMeshVertex{[4]float32{b[0] - fringe, b[1] - fringe, solidU, solidV}},
+		tMeshVertex{[4]float32{b[2] + fringe, b[1] - fringe, solidU, solidV}},
+		tMeshVertex{[4]float32{b[2] + fringe, b[3] + fringe, solidU, solidV}},
+		tMeshVertex{[4]float32{b[0] - fringe, b[3] + fringe, solidU, solidV}},
+	)
+	ret.indices = append(ret.indices, base, base+1, base+2, base, base+2, base+3)
+	ret.nEdge = int32(len(ret.edges))
+	ret.indices = append(ret.indices, ret.edges...)
+	ret.edges = nil
+
+	ret.Setup()
+	return ret, nil
+}
+
+func (o *TTextMesh) vertex(p fontface.TPoint, x, u, v float32) uint32 {
+	px := p.X + x
+	o.bounds[0] = float32(math.Min(float64(o.bounds[0]), float64(px)))
+	o.bounds[1] = float32(math.Min(float64(o.bounds[1]), float64(p.Y)))
+	o.bounds[2] = float32(math.Max(float64(o.bounds[2]), float64(px)))
+	o.bounds[3] = float32(math.Max(float64(o.bounds[3]), float64(p.Y)))
+	o.vertices = append(o.vertices, tMeshVertex{[4]float32{px, p.Y, u, v}})
+	return uint32(len(o.vertices) - 1)
+}
+
+// edgeQuad adds the quad of the edge pass along the straight edge p0, p1.
+// Its v is the signed distance to the edge and u is 0, so u*u-v measures
+// the distance just like for the curves.
+func (o *TTextMesh) edgeQuad(p0, p1 fontface.TPoint, x float32) {
+	dx, dy := p1.X-p0.X, p1.Y-p0.Y
+	l := float32(math.Hypot(float64(dx), float64(dy)))
+	if l == 0 {
+		return
+	}
+	nx, ny := -dy/l*fringe, dx/l*fringe
+	base := uint32(len(o.vertices))
+	o.vertices = append(o.vertices,
+		tMeshVertex{[4]float32{p0.X + x - nx, p0.Y - ny, 0, -fringe}},
+		tMeshVertex{[4]float32{p1.X + x - nx, p1.Y - ny, 0, -fringe}},
+		tMeshVertex{[4]float32{p1.X + x + nx, p1.Y + ny, 0, fringe}},
+		tMeshVertex{[4]float32{p0.X + x + nx, p0.Y + ny, 0, fringe}},
+	)
+	o.edges = append(o.edges, base, base+1, base+2, base, base+2, base+3)
+}
+
+func (o *TTextMesh) addOutline(outline fontface.TOutline, x float32) {
+	var anchor, first, last fontface.TPoint
+	fan := func(p0, p1 fontface.TPoint) {
+		o.indices = append(o.indices,
+			o.vertex(anchor, x, solidU, solidV),
+			o.vertex(p0, x, solidU, solidV),
+			o.vertex(p1, x, solidU, solidV))
+	}
+	line := func(p0, p1 fontface.TPoint) {
+		fan(p0, p1)
+		o.edgeQuad(p0, p1, x)
+	}
+	quad := func(p0, c, p1 fontface.TPoint) {
+		fan(p0, p1)
+		curve := []uint32{
+			o.vertex(p0, x, 0, 0),
+			o.vertex(c, x, 0.5, 0),
+			o.vertex(p1, x, 1, 1),
+		}
+		o.indices = append(o.indices, curve...)
+		o.edges = append(o.edges, curve...)
+	}
+	closeContour := func() {
+		if last != first {
+			line(last, first)
+		}
+	}
+	for i, seg := range outline {
+		switch seg.Op {
+		case fontface.MoveTo:
+			if i > 0 {
+				closeContour()
+			} else {
+				anchor = seg.Args[0]
+			}
+			first = seg.Args[0]
+			last = first
+		case fontface.LineTo:
+			line(last, seg.Args[0])
+			last = seg.Args[0]
+		case fontface.QuadTo:
+			quad(last, seg.Args[0], seg.Args[1])
+			last = seg.Args[1]
+		case fontface.CubeTo:
+			for _, q := range cubicToQuads(last, seg.Args[0], seg.Args[1], seg.Args[2]) {
+				quad(q[0], q[1], q[2])
+			}
+			last = seg.Args[2]
+		}
+	}
+	closeContour()
+}
+
+func lerpPoint(a, b fontface.TPoint, t float32) fontface.TPoint {
+	return fontface.TPoint{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t}
+}
+
+// cubicToQuads approximates a cubic curve by four quadratic ones.
+func cubicToQuads(p0, c0, c1, p1 fontface.TPoint) [][3]fontface.TPoint {
+	split := func(p0, c0, c1, p1 fontface.TPoint) ([4]fontface.TPoint, [4]fontface.TPoint) {
+		a := lerpPoint(p0, c0, 0.5)
+		b := lerpPoint(c0, c1, 0.5)
+		c := lerpPoint(c1, p1, 0.5)
+		d := lerpPoint(a, b, 0.5)
+		e := lerpPoint(b, c, 0.5)
+		m := lerpPoint(d, e, 0.5)
+		return [4]fontface.TPoint{p0, a, d, m}, [4]fontface.TPoint{m, e, c, p1}
+	}
+	ret := [][3]fontface.TPoint{}
+	l, r := split(p0, c0, c1, p1)
+	for _, half := range [][4]fontface.TPoint{l, r} {
+		ll, rr := split(half[0], half[1], half[2], half[3])
+		for _, c := range [][4]fontface.TPoint{ll, rr} {
+			// control point of the quadratic curve matching the tangents
+			ctrl := fontface.TPoint{
+				X: (3*(c[1].X+c[2].X) - c[0].X - c[3].X) / 4,
+				Y: (3*(c[1].Y+c[2].Y) - c[0].Y - c[3].Y) / 4,
+			}
+			ret = append(ret, [3]fontface.TPoint{c[0], ctrl, c[3]})
+		}
+	}
+	return ret
+}
+
+// Setup -
+func (o *TTextMesh) Setup() {
+	o.prog.Use()
+	o.vao = newVao(o.dev)
+	o.vbo = newArrayBuffer[tMeshVertex](o.dev)
+	o.ebo = newElementArrayBuffer[uint32](o.dev)
+
+	o.vao.Bind()
+	o.vbo.Bind()
+	if err := o.vbo.Data(o.vertices, gl.STATIC_DRAW); err != nil {
+		logPanicf("%v", err)
+	}
+	layout, err := NewVertexLayout[tMeshVertex]()
+	if err != nil {
+		logPanicf("%v", err)
+	}
+	if err := o.vao.ApplyLayout(o.vbo, layout, o.prog); err != nil {
+		logPanicf("%v", err)
+	}
+	o.ebo.Bind()
+	if err := o.ebo.Data(o.indices, gl.STATIC_DRAW); err != nil {
+		logPanicf("%v", err)
+	}
+	o.vao.Unbind()
+	o.vbo.Unbind()
+}
+
+// Bounds - returns the box of the tessellated text relative to the origin.
+func (o *TTextMesh) Bounds() (minX, minY, maxX, maxY float32) {
+	return o.bounds[0], o.bounds[1], o.bounds[2], o.bounds[3]
+}
+
+// Draw - draws the text with the baseline origin at x, y. The framebuffer
+// must have a stencil buffer.
+func (o *TTextMesh) Draw(x, y float32, screenW, screenH int, r, g, b, a float32) {
+	if o.nFill == 0 {
+		return
+	}
+	o.prog.Use()
+	mtx := mgl32.Ortho2D(float32(0), float32(screenW), float32(screenH), float32(0))
+	setUniform(o.prog, "Ortho", mtx)
+	setUniform(o.prog, "Offset", mgl32.Vec2{x, y})
+	setUniform(o.prog, "inColor", mgl32.Vec4{srgbToLinear(r), srgbToLinear(g), srgbToLinear(b), a})
+	setUniform(o.prog, "Edge", false)
+
+	o.dev.Disable(gl.DEPTH_TEST)
+	o.dev.Enable(gl.STENCIL_TEST)
+	o.vao.Bind()
+
+	// the fill pass counts the winding number in the low 7 bits of the
+	// stencil, both faces are drawn and counted in opposite directions
+	o.dev.Disable(gl.CULL_FACE)
+	o.dev.ColorMask(false, false, false, false)
+	o.dev.StencilMask(0x7f)
+	o.dev.StencilFunc(gl.ALWAYS, 0, 0xff)
+	o.dev.StencilOpSeparate(gl.FRONT, gl.KEEP, gl.KEEP, gl.INCR_WRAP)
+	o.dev.StencilOpSeparate(gl.BACK, gl.KEEP, gl.KEEP, gl.DECR_WRAP)
+	o.dev.DrawElements(gl.TRIANGLES, o.nFill, gl.UNSIGNED_INT, 0)
+
+	// the edge pass blends the coverage of the pixels outside of the fill
+	// and sets their high bit so that each one is blended once
+	o.dev.ColorMask(true, true, true, true)
+	o.dev.Enable(gl.BLEND)
+	o.dev.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	setUniform(o.prog, "Edge", true)
+	o.dev.StencilMask(0x80)
+	o.dev.StencilFunc(gl.EQUAL, 0, 0xff)
+	o.dev.StencilOp(gl.KEEP, gl.KEEP, gl.INVERT)
+	o.dev.DrawElements(gl.TRIANGLES, o.nEdge, gl.UNSIGNED_INT, int(o.nFill+6)*4)
+
+	// the cover pass paints nonzero areas and clears the stencil back
+	setUniform(o.prog, "Edge", false)
+	o.dev.StencilMask(0xff)
+	o.dev.StencilFunc(gl.NOTEQUAL, 0, 0x7f)
+	o.dev.StencilOp(gl.ZERO, gl.ZERO, gl.ZERO)
+	o.dev.DrawElements(gl.TRIANGLES, 6, gl.UNSIGNED_INT, int(o.nFill)*4)
+
+	o.vao.Unbind()
+	o.dev.Disable(gl.STENCIL_TEST)
+}
@@ -0,0 +1,181 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"unsafe"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+)
+
+// TTexture - a 2D texture. Gray and Alpha images are stored as R8, the
+// others as RGBA8 with premultiplied alpha.
+type TTexture struct {
+	dev            *tDevice
+	id             uint32
+	w, h           int
+	internalFormat int32
+	format         uint32
+	bpp            int
+}
+
+// NewTexture - creates a texture of the size of img and uploads it. The
+// filtering is linear and the wrapping clamps to the edge.
+func NewTexture(img image.Image) (*TTexture, error) {
+	return newTexture(dev, img)
+}
+
+func newTexture(d *tDevice, img image.Image) (*TTexture, error) {
+	b := img.Bounds()
+	if b.Empty() {
+		return nil, fmt.Errorf("texture: empty image %v", b)
+	}
+	ret := &TTexture{dev: d, w: b.Dx(), h: b.Dy()}
+	ret.internalFormat, ret.format, ret.bpp = textureFormat(img)
+	pix, stride := texturePixels(img)
+
+	ret.id = d.GenTexture()
+	d.BindTexture(gl.TEXTURE_2D, ret.id)
+	d.setUnpack(stride / ret.bpp)
+	d.TexImage2D(gl.TEXTURE_2D, 0, ret.internalFormat, int32(ret.w), int32(ret.h),
+		ret.format, gl.UNSIGNED_BYTE, unsafe.Pointer(&pix[0]))
+	d.setUnpack(0)
+	d.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	d.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	d.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	d.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	d.BindTexture(gl.TEXTURE_2D, 0)
+	return ret, nil
+}
+
+// NewEmptyTexture - creates an RGBA8 texture with undefined contents, e.g.
+// to render into, see TFramebuffer.
+func NewEmptyTexture(w, h int) (*TTexture, error) {
+	return newEmptyTexture(dev, w, h)
+}
+
+func newEmptyTexture(d *tDevice, w, h int) (*TTexture, error) {
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("texture: invalid size %vx%v", w, h)
+	}
+	ret := &TTexture{dev: d, w: w, h: h, internalFormat: gl.RGBA8, format: gl.RGBA, bpp: 4}
+	ret.id = d.GenTexture()
+	d.BindTexture(gl.TEXTURE_2D, ret.id)
+	d.TexImage2D(gl.TEXTURE_2D, 0, ret.internalFormat, int32(w), int32(h),
+		ret.format, gl.UNSIGNED_BYTE, nil)
+	d.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	d.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	d.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	d.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	d.BindTexture(gl.TEXTURE_2D, 0)
+	return ret, nil
+}
+
+// textureFormat returns the internal format, the pixel format and the bytes
+// per pixel the image is stored with.
+func textureFormat(img image.Image) (int32, uint32, int) {
+	switch img.(type) {
+	case *image.Gray, *image.Alpha:
+		return gl.R8, gl.RED, 1
+	}
+	return gl.RGBA8, gl.RGBA, 4
+}
+
+// texturePixels returns the pixels of the image starting at the top left
+// corner of its bounds and the length of a row in bytes. Images of other
+// types than Gray, Alpha and RGBA are converted to RGBA.
+func texturePixels(img image.Image) ([]uint8, int) {
+	b := img.Bounds()
+	switch m := img.(type) {
+	case *image.Gray:
+		return m.Pix[m.PixOffset(b.Min.X, b.Min.Y):], m.Stride
+	case *image.Alpha:
+		return m.Pix[m.PixOffset(b.Min.X, b.Min.Y):], m.Stride
+	case *image.RGBA:
+		return m.Pix[m.PixOffset(b.Min.X, b.Min.Y):], m.Stride
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba.Pix, rgba.Stride
+}
+
+// setUnpack sets the row length of uploaded pixels, 0 means the width.
+func (o *tDevice) setUnpack(rowLength int) {
+	o.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	o.PixelStorei(gl.UNPACK_ROW_LENGTH, int32(rowLength))
+}
+
+// ID -
+func (o *TTexture) ID() uint32 { return o.id }
+
+// Size -
+func (o *TTexture) Size() image.Point { return image.Pt(o.w, o.h) }
+
+// Bind - binds the texture to the texture unit.
+func (o *TTexture) Bind(unit int) {
+	o.dev.ActiveTexture(gl.TEXTURE0 + uint32(unit))
+	o.dev.BindTexture(gl.TEXTURE_2D, o.id)
+}
+
+// Unbind -
+func (o *TTexture) Unbind(unit int) {
+	o.dev.ActiveTexture(gl.TEXTURE0 + uint32(unit))
+	o.dev.BindTexture(gl.TEXTURE_2D, 0)
+}
+
+// SubImage - replaces the pixels at x, y with img, which must be stored in
+// the same format as the texture.
+func (o *TTexture) SubImage(x, y int, img image.Image) error {
+	b := img.Bounds()
+	if b.Empty() {
+		return nil
+	}
+	if !image.Rect(x, y, x+b.Dx(), y+b.Dy()).In(image.Rect(0, 0, o.w, o.h)) {
+		return fmt.Errorf("texture: %v at %v,%v is out of %vx%v", b.Size(), x, y, o.w, o.h)
+	}
+	if _, format, _ := textureFormat(img); format != o.format {
+		return fmt.Errorf("texture: %T does not match the format of the texture", img)
+	}
+	pix, stride := texturePixels(img)
+
+	o.dev.BindTexture(gl.TEXTURE_2D, o.id)
+	o.dev.setUnpack(stride / o.bpp)
+	o.dev.TexSubImage2D(gl.TEXTURE_2D, 0, int32(x), int32(y), int32(b.Dx()), int32(b.Dy()),
+		o.format, gl.UNSIGNED_BYTE, unsafe.Pointer(&pix[0]))
+	o.dev.setUnpack(0)
+	o.dev.BindTexture(gl.TEXTURE_2D, 0)
+	return nil
+}
+
+// SetFilter - e.g. gl.NEAREST, gl.LINEAR or gl.LINEAR_MIPMAP_LINEAR for min.
+func (o *TTexture) SetFilter(min, mag int32) {
+	o.dev.BindTexture(gl.TEXTURE_2D, o.id)
+	o.dev.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, min)
+	o.dev.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, mag)
+	o.dev.BindTexture(gl.TEXTURE_2D, 0)
+}
+
+// SetWrap - e.g. gl.CLAMP_TO_EDGE, gl.REPEAT or gl.MIRRORED_REPEAT.
+func (o *TTexture) SetWrap(s, t int32) {
+	o.dev.BindTexture(gl.TEXTURE_2D, o.id)
+	o.dev.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, s)
+	o.dev.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, t)
+	o.dev.BindTexture(gl.TEXTURE_2D, 0)
+}
+
+// GenerateMipmaps - builds the mipmaps from the current pixels, call it
+// again after SubImage.
+func (o *TTexture) GenerateMipmaps() {
+	o.dev.BindTexture(gl.TEXTURE_2D, o.id)
+	o.dev.GenerateMipmap(gl.TEXTURE_2D)
+	o.dev.BindTexture(gl.TEXTURE_2D, 0)
+}
+
+// Delete -
+func (o *TTexture) Delete() {
+	if o.id != 0 {
+		o.dev.DeleteTexture(o.id)
+		o.id = 0
+	}
+}
@@ -2,13 +2,22 @@ package theme
 
 import (
 	"image/color"
-	"unsafe"
+
+	"github.com/macroblock/exp/pkg/ui/fontface"
 )
 
 // TTheme -
 type TTheme struct {
-	DPI     float64
-	FonFace unsafe.Pointer
+	DPI      float64
+	FontFace *fontface.TFontFace
+	// TextGamma above 1 thickens light text and thins dark text, which
+	// blending in sRGB space makes look thin and bold respectively.
+	TextGamma float64
+	// TextContrast above 0 sharpens glyph edges, below 0 softens them.
+	TextContrast float64
+	// SRGB asks for an sRGB framebuffer so blending happens in linear
+	// space, TextGamma should stay 1 then.
+	SRGB    bool
 	palette *TPalette
 }
 
@@ -27,8 +36,12 @@ const (
 	paletteLen
 )
 
-// DefaultDPI -
-const DefaultDPI = 72.0
+// defaults
+const (
+	DefaultDPI       = 72.0
+	DefaultFontSize  = 14
+	DefaultTextGamma = 1.0
+)
 
 var (
 	defaultPalette = TPalette{
@@ -49,6 +62,36 @@ func (o *TTheme) GetDPI() float64 {
 	return o.DPI
 }
 
+// GetFontFace - returns the regular Go font of DefaultFontSize if the theme
+// has no face.
+func (o *TTheme) GetFontFace() (*fontface.TFontFace, error) {
+	if o == nil || o.FontFace == nil {
+		return fontface.Go(fontface.GoRegular, DefaultFontSize)
+	}
+	return o.FontFace, nil
+}
+
+// GetTextGamma -
+func (o *TTheme) GetTextGamma() float64 {
+	if o == nil || o.TextGamma <= 0.0 {
+		return DefaultTextGamma
+	}
+	return o.TextGamma
+}
+
+// GetTextContrast -
+func (o *TTheme) GetTextContrast() float64 {
+	if o == nil {
+		return 0.0
+	}
+	return o.TextContrast
+}
+
+// GetSRGB -
+func (o *TTheme) GetSRGB() bool {
+	return o != nil && o.SRGB
+}
+
 // GetPalette -
 func (o *TTheme) GetPalette() *TPalette {
 	if o == nil || o.palette == nil {
@@ -1,12 +1,12 @@
 package ui
 
-import "C"
 import (
 	"fmt"
 	"image"
 	"image/color"
+	"math"
 	"runtime"
-	"unsafe"
+	"strings"
 
 	gl "github.com/go-gl/gl/v3.1/gles2"
 	"github.com/macroblock/exp/pkg/ui/theme"
@@ -14,24 +14,35 @@ import (
 )
 
 func logErrorf(format string, args ...interface{}) error {
-	return fmt.Errorf(format, args)
+	return fmt.Errorf(format, args...)
 }
 
 func logPanicf(format string, args ...interface{}) {
-	panic(fmt.Sprintf(format, args))
+	panic(fmt.Sprintf(format, args...))
 }
 
 // TContext -
 type TContext struct {
+	dev      *tDevice
 	valid    bool
+	srgb     bool
+	target   *TFramebuffer // of headless contexts
 	window   *sdl.Window
 	context  sdl.GLContext
 	userData interface{}
 }
 
+// srgbFramebuffer is set when the current context encodes the colors it
+// writes to sRGB, shaders must output linear colors then.
+var srgbFramebuffer bool
+
 // NewContext -
 func NewContext(title string, w, h int) (*TContext, error) {
-	ctx := &TContext{}
+	return newContext(title, w, h, false)
+}
+
+func newContext(title string, w, h int, headless bool) (*TContext, error) {
+	ctx := &TContext{dev: dev}
 	err := error(nil)
 	runtime.LockOSThread()
 	err = sdl.Init(sdl.INIT_EVERYTHING)
@@ -44,10 +55,30 @@ func NewContext(title string, w, h int) (*TContext, error) {
 		return nil, logErrorf("sdl.GLSetAttribute: %v", err)
 	}
 
+	// vector text (TTextMesh) is filled through the stencil buffer
+	err = sdl.GLSetAttribute(sdl.GL_STENCIL_SIZE, 8)
+	if err != nil {
+		return nil, logErrorf("sdl.GLSetAttribute: %v", err)
+	}
+
+	if theme.Default.GetSRGB() && !headless {
+		err = sdl.GLSetAttribute(sdl.GL_FRAMEBUFFER_SRGB_CAPABLE, 1)
+		if err != nil {
+			return nil, logErrorf("sdl.GLSetAttribute: %v", err)
+		}
+	}
+
+	flags := uint32(sdl.WINDOW_RESIZABLE | sdl.WINDOW_OPENGL)
+	if headless {
+		if err := setHeadlessAttributes(); err != nil {
+			return nil, err
+		}
+		flags = sdl.WINDOW_HIDDEN | sdl.WINDOW_OPENGL
+	}
 	ctx.window, err = sdl.CreateWindow(title,
 		sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
 		int32(w), int32(h),
-		sdl.WINDOW_RESIZABLE|sdl.WINDOW_OPENGL)
+		flags)
 	if err != nil {
 		return nil, logErrorf("sdl.CreateWindow: %v", err)
 	}
@@ -57,14 +88,46 @@ func NewContext(title string, w, h int) (*TContext, error) {
 		return nil, logErrorf("sdl.GLCreateContext: %v", err)
 	}
 
-	err = gl.Init()
+	err = ctx.dev.Init()
 	if err != nil {
 		return nil, logErrorf("gles.Init: %v", err)
 	}
+	if theme.Default.GetSRGB() && !headless {
+		ctx.srgb = ctx.enableSRGB()
+	}
+	srgbFramebuffer = ctx.srgb
+	if headless {
+		ctx.target, err = newFramebuffer(ctx.dev, w, h)
+		if err != nil {
+			return nil, logErrorf("headless: %v", err)
+		}
+		ctx.target.Bind()
+	}
 	ctx.valid = true
 	return ctx, nil
 }
 
+// enableSRGB turns the sRGB encoding on if the default framebuffer supports
+// it and reports whether it does.
+func (o *TContext) enableSRGB() bool {
+	encoding := o.dev.GetFramebufferAttachmentParameteri(gl.FRAMEBUFFER, gl.BACK,
+		gl.FRAMEBUFFER_ATTACHMENT_COLOR_ENCODING)
+	if o.dev.GetError() != gl.NO_ERROR || encoding != gl.SRGB {
+		return false
+	}
+	// without the control extension the encoding is always on
+	if strings.Contains(o.dev.GetString(gl.EXTENSIONS), "GL_EXT_sRGB_write_control") {
+		o.dev.Enable(gl.FRAMEBUFFER_SRGB_EXT)
+	}
+	return true
+}
+
+// SRGB - reports whether the framebuffer blends in linear space, see
+// theme.TTheme.SRGB.
+func (o *TContext) SRGB() bool {
+	return o.srgb
+}
+
 // Close -
 func Close(ctx **TContext) {
 	if ctx == nil || *ctx == nil {
@@ -72,6 +135,10 @@ func Close(ctx **TContext) {
 	}
 	c := *ctx
 	c.valid = false
+	if c.target != nil {
+		c.target.Delete()
+		c.target = nil
+	}
 	if c.context != nil {
 		sdl.GLDeleteContext(c.context)
 		c.context = nil
@@ -97,12 +164,6 @@ func (o *TContext) Close() {
 	Close(&o)
 }
 
-func glGetString(val uint32) string {
-	x := gl.GetString(val)
-	p := (*C.char)(unsafe.Pointer(x))
-	return C.GoString(p)
-}
-
 // SetUserData -
 func (o *TContext) SetUserData(data interface{}) {
 	if o.Invalid() {
@@ -130,10 +191,10 @@ func (o *TContext) Info() string {
 	ret += fmt.Sprintf("  version: %v\n", v)
 
 	ret += fmt.Sprintf("opengl\n")
-	ret += fmt.Sprintf("  version       : %v\n", glGetString(gl.VERSION))
-	ret += fmt.Sprintf("  shader version: %v\n", glGetString(gl.SHADING_LANGUAGE_VERSION))
-	ret += fmt.Sprintf("  vendor        : %v\n", glGetString(gl.VENDOR))
-	ret += fmt.Sprintf("  renderer      : %v\n", glGetString(gl.RENDERER))
+	ret += fmt.Sprintf("  version       : %v\n", o.dev.GetString(gl.VERSION))
+	ret += fmt.Sprintf("  shader version: %v\n", o.dev.GetString(gl.SHADING_LANGUAGE_VERSION))
+	ret += fmt.Sprintf("  vendor        : %v\n", o.dev.GetString(gl.VENDOR))
+	ret += fmt.Sprintf("  renderer      : %v\n", o.dev.GetString(gl.RENDERER))
 	return ret
 }
 
@@ -159,15 +220,32 @@ func colorToFloat32(color color.Color) (float32, float32, float32, float32) {
 	return fr, fg, fb, fa
 }
 
+// srgbToLinear decodes a color channel if the framebuffer encodes them.
+func srgbToLinear(c float32) float32 {
+	if !srgbFramebuffer {
+		return c
+	}
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return float32(math.Pow((float64(c)+0.055)/1.055, 2.4))
+}
+
+// shaderColor converts a color to the space shaders should output.
+func shaderColor(c color.Color) (float32, float32, float32, float32) {
+	r, g, b, a := colorToFloat32(c)
+	return srgbToLinear(r), srgbToLinear(g), srgbToLinear(b), a
+}
+
 // Draw -
 func (o *TContext) Draw() {
 	if o.Invalid() {
 		logPanicf("invalid context\n")
 	}
 	pal := theme.Default.GetPalette()
-	r, g, b, a := colorToFloat32(pal[theme.Dark])
-	gl.ClearColor(r, g, b, a)
-	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	r, g, b, a := shaderColor(pal[theme.Dark])
+	o.dev.ClearColor(r, g, b, a)
+	o.dev.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT | gl.STENCIL_BUFFER_BIT)
 }
 
 // Flush -
@@ -175,5 +253,9 @@ func (o *TContext) Flush() {
 	if o.Invalid() {
 		logPanicf("invalid context\n")
 	}
+	if o.target != nil {
+		o.dev.Finish()
+		return
+	}
 	o.window.GLSwap()
 }
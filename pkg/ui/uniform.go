@@ -0,0 +1,178 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// ErrUnknownUniform - the program has no active uniform of the name. The
+// compiler drops uniforms the shaders do not use, so callers may ignore it.
+var ErrUnknownUniform = errors.New("unknown uniform")
+
+// tUniformValue is a value flattened for the Uniform*v commands.
+type tUniformValue struct {
+	typ   uint32 // the GLSL type of an element
+	count int
+	f     []float32
+	i     []int32
+	u     []uint32
+}
+
+// uniformValue flattens the supported types of SetUniform.
+func uniformValue(value interface{}) (tUniformValue, bool) {
+	floats := func(typ uint32, count int, f []float32) (tUniformValue, bool) {
+		return tUniformValue{typ: typ, count: count, f: f}, true
+	}
+	switch v := value.(type) {
+	case float32:
+		return floats(gl.FLOAT, 1, []float32{v})
+	case mgl32.Vec2:
+		return floats(gl.FLOAT_VEC2, 1, v[:])
+	case mgl32.Vec3:
+		return floats(gl.FLOAT_VEC3, 1, v[:])
+	case mgl32.Vec4:
+		return floats(gl.FLOAT_VEC4, 1, v[:])
+	case mgl32.Mat3:
+		return floats(gl.FLOAT_MAT3, 1, v[:])
+	case mgl32.Mat4:
+		return floats(gl.FLOAT_MAT4, 1, v[:])
+	case []float32:
+		return floats(gl.FLOAT, len(v), v)
+	case []mgl32.Vec2:
+		f := make([]float32, 0, len(v)*2)
+		for _, x := range v {
+			f = append(f, x[:]...)
+		}
+		return floats(gl.FLOAT_VEC2, len(v), f)
+	case []mgl32.Vec3:
+		f := make([]float32, 0, len(v)*3)
+		for _, x := range v {
+			f = append(f, x[:]...)
+		}
+		return floats(gl.FLOAT_VEC3, len(v), f)
+	case []mgl32.Vec4:
+		f := make([]float32, 0, len(v)*4)
+		for _, x := range v {
+			f = append(f, x[:]...)
+		}
+		return floats(gl.FLOAT_VEC4, len(v), f)
+	case []mgl32.Mat3:
+		f := make([]float32, 0, len(v)*9)
+		for _, x := range v {
+			f = append(f, x[:]...)
+		}
+		return floats(gl.FLOAT_MAT3, len(v), f)
+	case []mgl32.Mat4:
+		f := make([]float32, 0, len(v)*16)
+		for _, x := range v {
+			f = append(f, x[:]...)
+		}
+		return floats(gl.FLOAT_MAT4, len(v), f)
+	case int:
+		return tUniformValue{typ: gl.INT, count: 1, i: []int32{int32(v)}}, true
+	case int32:
+		return tUniformValue{typ: gl.INT, count: 1, i: []int32{v}}, true
+	case []int32:
+		return tUniformValue{typ: gl.INT, count: len(v), i: v}, true
+	case uint32:
+		return tUniformValue{typ: gl.UNSIGNED_INT, count: 1, u: []uint32{v}}, true
+	case []uint32:
+		return tUniformValue{typ: gl.UNSIGNED_INT, count: len(v), u: v}, true
+	case bool:
+		b := int32(0)
+		if v {
+			b = 1
+		}
+		return tUniformValue{typ: gl.BOOL, count: 1, i: []int32{b}}, true
+	}
+	return tUniformValue{}, false
+}
+
+func isSampler(typ uint32) bool {
+	switch typ {
+	case gl.SAMPLER_2D, gl.SAMPLER_3D, gl.SAMPLER_CUBE, gl.SAMPLER_2D_SHADOW,
+		gl.SAMPLER_2D_ARRAY, gl.SAMPLER_2D_ARRAY_SHADOW, gl.SAMPLER_CUBE_SHADOW,
+		gl.INT_SAMPLER_2D, gl.INT_SAMPLER_3D, gl.INT_SAMPLER_CUBE, gl.INT_SAMPLER_2D_ARRAY,
+		gl.UNSIGNED_INT_SAMPLER_2D, gl.UNSIGNED_INT_SAMPLER_3D, gl.UNSIGNED_INT_SAMPLER_CUBE,
+		gl.UNSIGNED_INT_SAMPLER_2D_ARRAY:
+		return true
+	}
+	return false
+}
+
+// setUniform panics on errors other than ErrUnknownUniform.
+func setUniform(prog *TProgram, name string, value interface{}) {
+	if err := prog.SetUniform(name, value); err != nil && !errors.Is(err, ErrUnknownUniform) {
+		logPanicf("%v", err)
+	}
+}
+
+// Uniform - returns the active uniform of the name, arrays may be named
+// with or without "[0]". The lookups are cached.
+func (o *TProgram) Uniform(name string) (TAttribParams, error) {
+	if o.uniformMap == nil {
+		o.uniformMap = map[string]TAttribParams{}
+		for _, u := range o.UniformParams() {
+			o.uniformMap[u.name] = u
+			o.uniformMap[strings.TrimSuffix(u.name, "[0]")] = u
+		}
+	}
+	u, ok := o.uniformMap[name]
+	if !ok {
+		return u, fmt.Errorf("program %v: %w %q", o.id, ErrUnknownUniform, name)
+	}
+	return u, nil
+}
+
+// SetUniform - makes the program active and sets the uniform. The value
+// must match the declared type: float32, mgl32.Vec2..4, mgl32.Mat3..4,
+// int32 or int, uint32, bool, or a slice of one of them for arrays.
+// Samplers take the texture unit as int32 or int.
+func (o *TProgram) SetUniform(name string, value interface{}) error {
+	u, err := o.Uniform(name)
+	if err != nil {
+		return err
+	}
+	v, ok := uniformValue(value)
+	if !ok {
+		return fmt.Errorf("uniform %q: unsupported value of type %T", name, value)
+	}
+	if v.typ != u.typ && !(v.typ == gl.INT && isSampler(u.typ)) {
+		want, _, _ := glDescribeType(u.typ)
+		return fmt.Errorf("uniform %q is %v, not %T", name, want, value)
+	}
+	if size := int(u.num); size < 1 && v.count > 1 || size >= 1 && v.count > size {
+		return fmt.Errorf("uniform %q holds %v elements, not %v", name, u.num, v.count)
+	}
+	if v.count == 0 {
+		return nil
+	}
+	if o.dev.activeProgram != o {
+		o.Use()
+	}
+
+	loc := int32(u.index)
+	switch v.typ {
+	case gl.FLOAT:
+		o.dev.Uniform1fv(loc, v.f)
+	case gl.FLOAT_VEC2:
+		o.dev.Uniform2fv(loc, v.f)
+	case gl.FLOAT_VEC3:
+		o.dev.Uniform3fv(loc, v.f)
+	case gl.FLOAT_VEC4:
+		o.dev.Uniform4fv(loc, v.f)
+	case gl.FLOAT_MAT3:
+		o.dev.UniformMatrix3fv(loc, false, v.f)
+	case gl.FLOAT_MAT4:
+		o.dev.UniformMatrix4fv(loc, false, v.f)
+	case gl.INT, gl.BOOL:
+		o.dev.Uniform1iv(loc, v.i)
+	case gl.UNSIGNED_INT:
+		o.dev.Uniform1uiv(loc, v.u)
+	}
+	return nil
+}
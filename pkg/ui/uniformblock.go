@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+	"github.com/macroblock/imed/pkg/misc"
+)
+
+// TUniformBlock - an active uniform block of a program.
+type TUniformBlock struct {
+	Name    string
+	Index   uint32
+	Size    int // in bytes
+	Binding uint32
+	Members []TUniformMember // in the order of their offsets
+}
+
+// TUniformMember - a uniform of a block. Members of instanced blocks are
+// named "Block.Member", arrays "Member[0]".
+type TUniformMember struct {
+	Name         string
+	Type         uint32
+	Num          int32 // the number of elements of an array, 1 otherwise
+	Offset       int
+	ArrayStride  int
+	MatrixStride int
+}
+
+// UniformBlocks -
+func (o *TProgram) UniformBlocks() []TUniformBlock {
+	if o.blocks != nil {
+		return o.blocks
+	}
+	count := misc.MaxInt(0, int(o.dev.GetProgrami(o.id, gl.ACTIVE_UNIFORM_BLOCKS)))
+	ret := []TUniformBlock{}
+	param := []int32{0}
+	for i := uint32(0); i < uint32(count); i++ {
+		block := TUniformBlock{Name: o.dev.GetActiveUniformBlockName(o.id, i), Index: i}
+		o.dev.GetActiveUniformBlockiv(o.id, i, gl.UNIFORM_BLOCK_DATA_SIZE, param)
+		block.Size = int(param[0])
+		o.dev.GetActiveUniformBlockiv(o.id, i, gl.UNIFORM_BLOCK_BINDING, param)
+		block.Binding = uint32(param[0])
+		o.dev.GetActiveUniformBlockiv(o.id, i, gl.UNIFORM_BLOCK_ACTIVE_UNIFORMS, param)
+		n := misc.MaxInt(0, int(param[0]))
+		if n > 0 {
+			block.Members = o.blockMembers(i, n)
+		}
+		ret = append(ret, block)
+	}
+	return ret
+}
+
+func (o *TProgram) blockMembers(block uint32, n int) []TUniformMember {
+	params := make([]int32, n)
+	o.dev.GetActiveUniformBlockiv(o.id, block, gl.UNIFORM_BLOCK_ACTIVE_UNIFORM_INDICES, params)
+	indices := make([]uint32, n)
+	for i, index := range params {
+		indices[i] = uint32(index)
+	}
+	query := func(pname uint32) []int32 {
+		ret := make([]int32, n)
+		o.dev.GetActiveUniformsiv(o.id, indices, pname, ret)
+		return ret
+	}
+	offsets := query(gl.UNIFORM_OFFSET)
+	arrayStrides := query(gl.UNIFORM_ARRAY_STRIDE)
+	matrixStrides := query(gl.UNIFORM_MATRIX_STRIDE)
+
+	ret := make([]TUniformMember, n)
+	for i, index := range indices {
+		m := &ret[i]
+		m.Name, m.Num, m.Type = o.dev.GetActiveUniform(o.id, index)
+		m.Offset = int(offsets[i])
+		m.ArrayStride = int(arrayStrides[i])
+		m.MatrixStride = int(matrixStrides[i])
+	}
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].Offset < ret[j].Offset })
+	return ret
+}
+
+// UniformBlock - returns the active uniform block of the name.
+func (o *TProgram) UniformBlock(name string) (TUniformBlock, error) {
+	for _, block := range o.UniformBlocks() {
+		if block.Name == name {
+			return block, nil
+		}
+	}
+	return TUniformBlock{}, fmt.Errorf("program %v: %w block %q", o.id, ErrUnknownUniform, name)
+}
+
+// BindUniformBlock - makes the block read the buffer bound to the binding
+// point, see TUniformBuffer.
+func (o *TProgram) BindUniformBlock(name string, binding uint32) error {
+	o.blocks = o.UniformBlocks()
+	block, err := o.UniformBlock(name)
+	if err != nil {
+		return err
+	}
+	o.dev.UniformBlockBinding(o.id, block.Index, binding)
+	o.blocks[block.Index].Binding = binding
+	return nil
+}
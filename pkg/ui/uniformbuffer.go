@@ -0,0 +1,317 @@
+package ui
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unsafe"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// TUniformBuffer - a buffer for uniform blocks declared with
+// layout(std140), filled from the struct T. It stays bound to its binding
+// point, so every program attached to it reads the same data:
+//
+//	type tFrame struct {
+//		Projection mgl32.Mat4
+//		Time       float32
+//		Colors     [4]mgl32.Vec4 `gl:"ThemeColors"`
+//	}
+//	frame, err := NewUniformBuffer[tFrame](0)
+//	err = frame.Attach(prog, "Frame") // for each program with the block
+//	frame.Set(tFrame{...})            // once per frame
+//
+// Fields are named as in NewVertexLayout. Scalars are float32, int32,
+// uint32 and bool; arrays of 2..4 scalars are vectors unless tagged
+// `gl:",array"`; mgl32 matrices are matrices; other arrays and structs
+// are arrays and structs of the block.
+type TUniformBuffer[T any] struct {
+	TBuffer
+	binding uint32
+	layout  tStd140
+	data    []byte
+}
+
+// tStd140 - where the fields of a struct go in a std140 block.
+type tStd140 struct {
+	copies  []tStd140Copy
+	members []tStd140Member
+	size    int
+}
+
+// tStd140Copy - n scalars from src in the struct to dst in the block.
+type tStd140Copy struct {
+	src  uintptr
+	dst  int
+	n    int
+	bool bool // 1 byte in Go, 4 in GLSL
+}
+
+// tStd140Member - a member of the block as the program would report it.
+type tStd140Member struct {
+	name   string
+	typ    uint32
+	num    int
+	offset int
+}
+
+var std140Matrices = map[reflect.Type][3]uint32{ // type, columns, rows
+	reflect.TypeOf(mgl32.Mat2{}):   {gl.FLOAT_MAT2, 2, 2},
+	reflect.TypeOf(mgl32.Mat3{}):   {gl.FLOAT_MAT3, 3, 3},
+	reflect.TypeOf(mgl32.Mat4{}):   {gl.FLOAT_MAT4, 4, 4},
+	reflect.TypeOf(mgl32.Mat2x3{}): {gl.FLOAT_MAT3x2, 3, 2},
+	reflect.TypeOf(mgl32.Mat2x4{}): {gl.FLOAT_MAT4x2, 4, 2},
+	reflect.TypeOf(mgl32.Mat3x2{}): {gl.FLOAT_MAT2x3, 2, 3},
+	reflect.TypeOf(mgl32.Mat3x4{}): {gl.FLOAT_MAT4x3, 4, 3},
+	reflect.TypeOf(mgl32.Mat4x2{}): {gl.FLOAT_MAT2x4, 2, 4},
+	reflect.TypeOf(mgl32.Mat4x3{}): {gl.FLOAT_MAT3x4, 3, 4},
+}
+
+var std140Vectors = map[reflect.Kind][4]uint32{
+	reflect.Float32: {gl.FLOAT, gl.FLOAT_VEC2, gl.FLOAT_VEC3, gl.FLOAT_VEC4},
+	reflect.Int32:   {gl.INT, gl.INT_VEC2, gl.INT_VEC3, gl.INT_VEC4},
+	reflect.Uint32:  {gl.UNSIGNED_INT, gl.UNSIGNED_INT_VEC2, gl.UNSIGNED_INT_VEC3, gl.UNSIGNED_INT_VEC4},
+	reflect.Bool:    {gl.BOOL, gl.BOOL_VEC2, gl.BOOL_VEC3, gl.BOOL_VEC4},
+}
+
+// NewUniformBuffer - creates the buffer and binds it to the binding point.
+func NewUniformBuffer[T any](binding uint32) (*TUniformBuffer[T], error) {
+	var zero T
+	t := reflect.TypeOf(&zero).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("uniform buffer of %v: not a struct", t)
+	}
+	ret := &TUniformBuffer[T]{binding: binding}
+	size, _, err := ret.layout.add("", t, false, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("uniform buffer of %v: %v", t, err)
+	}
+	ret.layout.size = size
+	ret.data = make([]byte, size)
+	ret.TBuffer = TBuffer{dev: dev, id: dev.GenBuffer(), len: 1, stride: size, size: size, usageHint: gl.DYNAMIC_DRAW}
+	ret.dev.BindBuffer(gl.UNIFORM_BUFFER, ret.id)
+	ret.dev.BufferData(gl.UNIFORM_BUFFER, size, nil, ret.usageHint)
+	ret.Bind()
+	return ret, nil
+}
+
+// Binding - the binding point of the buffer.
+func (o *TUniformBuffer[T]) Binding() uint32 { return o.binding }
+
+// Bind - binds the buffer to its binding point again, e.g. after another
+// buffer took it.
+func (o *TUniformBuffer[T]) Bind() {
+	o.dev.BindBufferBase(gl.UNIFORM_BUFFER, o.binding, o.id)
+}
+
+// Unbind - frees the binding point.
+func (o *TUniformBuffer[T]) Unbind() {
+	o.dev.BindBufferBase(gl.UNIFORM_BUFFER, o.binding, 0)
+}
+
+// Set - uploads the value laid out per std140.
+func (o *TUniformBuffer[T]) Set(value T) {
+	o.layout.pack(o.data, unsafe.Pointer(&value))
+	o.dev.BindBuffer(gl.UNIFORM_BUFFER, o.id)
+	o.dev.BufferSubData(gl.UNIFORM_BUFFER, 0, len(o.data), unsafe.Pointer(&o.data[0]))
+}
+
+// Attach - checks that the members of the block of the program are where
+// the buffer puts them and binds the block to the binding point of the
+// buffer. Fields the program does not use are ignored.
+func (o *TUniformBuffer[T]) Attach(prog *TProgram, blockName string) error {
+	block, err := prog.UniformBlock(blockName)
+	if err != nil {
+		return err
+	}
+	if block.Size > o.size {
+		return fmt.Errorf("uniform block %q: %v bytes, the buffer of %T has %v",
+			blockName, block.Size, *new(T), o.size)
+	}
+	members := map[string]tStd140Member{}
+	for _, m := range o.layout.members {
+		members[m.name] = m
+	}
+	for _, m := range block.Members {
+		name := strings.TrimSuffix(strings.TrimPrefix(m.Name, blockName+"."), "[0]")
+		field, ok := members[name]
+		switch {
+		case !ok:
+			return fmt.Errorf("uniform block %q: no field of %T for member %q", blockName, *new(T), m.Name)
+		case field.typ != m.Type:
+			want, _, _ := glDescribeType(m.Type)
+			got, _, _ := glDescribeType(field.typ)
+			return fmt.Errorf("uniform block %q: member %q is %v, not %v", blockName, m.Name, want, got)
+		case field.offset != m.Offset:
+			return fmt.Errorf("uniform block %q: member %q is at %v, not %v; is the block std140?",
+				blockName, m.Name, m.Offset, field.offset)
+		case int(m.Num) > field.num:
+			return fmt.Errorf("uniform block %q: member %q holds %v elements, not %v",
+				blockName, m.Name, m.Num, field.num)
+		}
+	}
+	return prog.BindUniformBlock(blockName, o.binding)
+}
+
+// std140Basic describes scalars, vectors and matrices: the GL type, the number
+// of columns and rows.
+func std140Basic(t reflect.Type, array bool) (uint32, int, int, bool) {
+	if m, ok := std140Matrices[t]; ok {
+		return m[0], int(m[1]), int(m[2]), true
+	}
+	if v, ok := std140Vectors[t.Kind()]; ok {
+		return v[0], 1, 1, true
+	}
+	if t.Kind() == reflect.Array && !array && 2 <= t.Len() && t.Len() <= 4 {
+		if v, ok := std140Vectors[t.Elem().Kind()]; ok {
+			return v[t.Len()-1], 1, t.Len(), true
+		}
+	}
+	return 0, 0, 0, false
+}
+
+func roundUp(x, align int) int {
+	return (x + align - 1) / align * align
+}
+
+// add lays t out at dst, src is its offset in the struct. It returns the
+// size and the base alignment of t.
+func (o *tStd140) add(name string, t reflect.Type, array bool, src uintptr, dst int) (int, int, error) {
+	if typ, cols, rows, ok := std140Basic(t, array); ok {
+		if name != "" {
+			o.members = append(o.members, tStd140Member{name: name, typ: typ, num: 1, offset: dst})
+		}
+		align := 4
+		switch {
+		case cols > 1:
+			align = 16
+		case rows == 2:
+			align = 8
+		case rows > 2:
+			align = 16
+		}
+		size := rows * 4
+		elem := t
+		if t.Kind() == reflect.Array {
+			elem = t.Elem()
+		}
+		for c := 0; c < cols; c++ {
+			o.copies = append(o.copies, tStd140Copy{src: src + uintptr(c*rows)*elem.Size(),
+				dst: dst + c*16, n: rows, bool: elem.Kind() == reflect.Bool})
+		}
+		if cols > 1 {
+			size = cols * 16
+		}
+		return size, align, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Array:
+		if t.Len() == 0 {
+			return 0, 0, fmt.Errorf("%v: empty array", name)
+		}
+		elem := t.Elem()
+		typ, _, _, basic := std140Basic(elem, false)
+		if !basic && elem.Kind() == reflect.Array {
+			return 0, 0, fmt.Errorf("%v: arrays of arrays are not supported by GLSL ES", name)
+		}
+		if basic {
+			o.members = append(o.members, tStd140Member{name: name, typ: typ, num: t.Len(), offset: dst})
+		}
+		stride := 0
+		for i := 0; i < t.Len(); i++ {
+			elemName := fmt.Sprintf("%v[%v]", name, i)
+			if basic {
+				elemName = ""
+			}
+			size, _, err := o.add(elemName, elem, false, src+uintptr(i)*elem.Size(), dst+i*stride)
+			if err != nil {
+				return 0, 0, err
+			}
+			stride = roundUp(size, 16)
+		}
+		return stride * t.Len(), 16, nil
+
+	case reflect.Struct:
+		prefix := ""
+		if name != "" {
+			prefix = name + "."
+		}
+		offset, align := 0, 16
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			fname, skip, farray, err := parseUniformTag(f)
+			if err != nil {
+				return 0, 0, fmt.Errorf("field %v: %v", f.Name, err)
+			}
+			if skip {
+				continue
+			}
+			// the alignment is known after a dry run
+			size, falign, err := (&tStd140{}).add(prefix+fname, f.Type, farray, 0, 0)
+			if err != nil {
+				return 0, 0, err
+			}
+			offset = roundUp(offset, falign)
+			if _, _, err = o.add(prefix+fname, f.Type, farray, src+f.Offset, dst+offset); err != nil {
+				return 0, 0, err
+			}
+			offset += size
+		}
+		if offset == 0 {
+			return 0, 0, fmt.Errorf("%v has no fields", t)
+		}
+		return roundUp(offset, align), align, nil
+	}
+	return 0, 0, fmt.Errorf("%v: unsupported type %v", name, t)
+}
+
+// parseUniformTag parses `gl:"name,array"`, name "-" skips the field.
+func parseUniformTag(f reflect.StructField) (string, bool, bool, error) {
+	tag, ok := f.Tag.Lookup("gl")
+	if !ok {
+		return f.Name, false, false, nil
+	}
+	opts := strings.Split(tag, ",")
+	name := f.Name
+	switch opts[0] {
+	case "-":
+		return name, true, false, nil
+	case "":
+	default:
+		name = opts[0]
+	}
+	array := false
+	for _, opt := range opts[1:] {
+		switch strings.TrimSpace(opt) {
+		case "array":
+			array = true
+		default:
+			return name, false, false, fmt.Errorf("unknown option %q", opt)
+		}
+	}
+	return name, false, array, nil
+}
+
+// pack writes the struct at src into dst, the padding is left as it is.
+func (o *tStd140) pack(dst []byte, src unsafe.Pointer) {
+	for _, c := range o.copies {
+		p := unsafe.Add(src, c.src)
+		if !c.bool {
+			copy(dst[c.dst:c.dst+c.n*4], unsafe.Slice((*byte)(p), c.n*4))
+			continue
+		}
+		for i, b := range unsafe.Slice((*bool)(p), c.n) {
+			v := uint32(0)
+			if b {
+				v = 1
+			}
+			*(*uint32)(unsafe.Pointer(&dst[c.dst+i*4])) = v
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package ui
+
+import (
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type tTestLight struct {
+	Pos mgl32.Vec3
+	On  bool
+}
+
+func TestStd140Offsets(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		size    int
+		members []tStd140Member
+	}{
+		{"vec3 then scalar", struct {
+			A mgl32.Vec3
+			B float32
+		}{}, 16, []tStd140Member{
+			{"A", gl.FLOAT_VEC3, 1, 0},
+			{"B", gl.FLOAT, 1, 12},
+		}},
+		{"scalar then vec3", struct {
+			A float32
+			B mgl32.Vec3
+		}{}, 32, []tStd140Member{
+			{"A", gl.FLOAT, 1, 0},
+			{"B", gl.FLOAT_VEC3, 1, 16},
+		}},
+		{"vec2", struct {
+			A float32
+			B mgl32.Vec2
+			C int32
+		}{}, 32, []tStd140Member{
+			{"A", gl.FLOAT, 1, 0},
+			{"B", gl.FLOAT_VEC2, 1, 8},
+			{"C", gl.INT, 1, 16},
+		}},
+		{"scalar array", struct {
+			A [3]float32 `gl:",array"`
+			B float32
+		}{}, 64, []tStd140Member{
+			{"A", gl.FLOAT, 3, 0},
+			{"B", gl.FLOAT, 1, 48},
+		}},
+		{"vec3 array", struct {
+			A [2]mgl32.Vec3
+			B float32
+		}{}, 48, []tStd140Member{
+			{"A", gl.FLOAT_VEC3, 2, 0},
+			{"B", gl.FLOAT, 1, 32},
+		}},
+		{"matrix columns", struct {
+			A float32
+			M mgl32.Mat3
+			N mgl32.Mat3x2 // 2 columns of vec3
+			B float32
+		}{}, 112, []tStd140Member{
+			{"A", gl.FLOAT, 1, 0},
+			{"M", gl.FLOAT_MAT3, 1, 16},
+			{"N", gl.FLOAT_MAT2x3, 1, 64},
+			{"B", gl.FLOAT, 1, 96},
+		}},
+		{"bools", struct {
+			A bool
+			B bool
+			C [2]bool
+			D [3]bool `gl:",array"`
+		}{}, 64, []tStd140Member{
+			{"A", gl.BOOL, 1, 0},
+			{"B", gl.BOOL, 1, 4},
+			{"C", gl.BOOL_VEC2, 1, 8},
+			{"D", gl.BOOL, 3, 16},
+		}},
+		{"struct", struct {
+			A float32
+			S tTestLight `gl:"Light"`
+			B float32
+		}{}, 48, []tStd140Member{
+			{"A", gl.FLOAT, 1, 0},
+			{"Light.Pos", gl.FLOAT_VEC3, 1, 16},
+			{"Light.On", gl.BOOL, 1, 28},
+			{"B", gl.FLOAT, 1, 32},
+		}},
+		{"struct array", struct {
+			L [2]tTestLight
+		}{}, 32, []tStd140Member{
+			{"L[0].Pos", gl.FLOAT_VEC3, 1, 0},
+			{"L[0].On", gl.BOOL, 1, 12},
+			{"L[1].Pos", gl.FLOAT_VEC3, 1, 16},
+			{"L[1].On", gl.BOOL, 1, 28},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout := tStd140{}
+			size, _, err := layout.add("", reflect.TypeOf(tt.value), false, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != tt.size {
+				t.Errorf("size %v, want %v", size, tt.size)
+			}
+			if !reflect.DeepEqual(layout.members, tt.members) {
+				t.Errorf("members\n%+v\nwant\n%+v", layout.members, tt.members)
+			}
+		})
+	}
+}
+
+func TestStd140Pack(t *testing.T) {
+	type tBlock struct {
+		Flag  bool
+		M     mgl32.Mat3
+		Light tTestLight
+	}
+	layout := tStd140{}
+	size, _, err := layout.add("", reflect.TypeOf(tBlock{}), false, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := tBlock{Flag: true, M: mgl32.Mat3{1, 2, 3, 4, 5, 6, 7, 8, 9},
+		Light: tTestLight{mgl32.Vec3{10, 11, 12}, true}}
+	data := make([]byte, size)
+	layout.pack(data, unsafe.Pointer(&value))
+
+	word := func(offset int) uint32 { return binary.LittleEndian.Uint32(data[offset:]) }
+	floats := map[int]float32{16: 1, 20: 2, 24: 3, 32: 4, 36: 5, 40: 6, 48: 7, 52: 8, 56: 9,
+		64: 10, 68: 11, 72: 12}
+	for offset, want := range floats {
+		if got := math.Float32frombits(word(offset)); got != want {
+			t.Errorf("at %v: %v, want %v", offset, got, want)
+		}
+	}
+	for _, offset := range []int{0, 76} {
+		if got := word(offset); got != 1 {
+			t.Errorf("bool at %v: %v, want 1", offset, got)
+		}
+	}
+}
+
+type tTestFrame struct {
+	Projection mgl32.Mat4
+	Time       float32
+	Colors     [2]mgl32.Vec4
+}
+
+func TestUniformBufferAttach(t *testing.T) {
+	frame := []TUniformMember{
+		{Name: "Frame.Projection", Type: gl.FLOAT_MAT4, Num: 1, Offset: 0, MatrixStride: 16},
+		{Name: "Frame.Time", Type: gl.FLOAT, Num: 1, Offset: 64},
+		{Name: "Frame.Colors[0]", Type: gl.FLOAT_VEC4, Num: 2, Offset: 80, ArrayStride: 16},
+	}
+	with := func(i int, m TUniformMember) []TUniformMember {
+		ret := append([]TUniformMember(nil), frame...)
+		ret[i] = m
+		return ret
+	}
+	tests := []struct {
+		name    string
+		size    int
+		members []TUniformMember
+		block   string
+		err     string
+	}{
+		{"match", 112, frame, "Frame", ""},
+		{"used fields", 80, frame[:2], "Frame", ""},
+		{"unknown block", 112, frame, "Other", "unknown uniform block"},
+		{"larger block", 128, frame, "Frame", "128 bytes"},
+		{"offset", 112, with(1, TUniformMember{Name: "Frame.Time", Type: gl.FLOAT, Num: 1, Offset: 68}),
+			"Frame", "is at 68, not 64"},
+		{"type", 112, with(1, TUniformMember{Name: "Frame.Time", Type: gl.INT, Num: 1, Offset: 64}),
+			"Frame", "is int, not float"},
+		{"elements", 112, with(2, TUniformMember{Name: "Frame.Colors[0]", Type: gl.FLOAT_VEC4, Num: 3, Offset: 80}),
+			"Frame", "holds 3 elements, not 2"},
+		{"no field", 112, with(1, TUniformMember{Name: "Frame.Scale", Type: gl.FLOAT, Num: 1, Offset: 64}),
+			"Frame", "no field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := useRecorder(t)
+			rec.DeclareUniformBlock("Frame", tt.size, tt.members...)
+			prog, err := NewProgram("vs", "fs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf, err := NewUniformBuffer[tTestFrame](2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = buf.Attach(prog, tt.block)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("got %v, want %q", err, tt.err)
+				}
+				if got := rec.Find("UniformBlockBinding"); len(got) != 0 {
+					t.Errorf("bound a mismatching block: %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if block, _ := prog.UniformBlock("Frame"); block.Binding != 2 {
+				t.Errorf("binding %v, want 2", block.Binding)
+			}
+		})
+	}
+}
@@ -1,34 +1,39 @@
 package ui
 
 import (
+	"fmt"
+
 	gl "github.com/go-gl/gl/v3.1/gles2"
 )
 
 // TVertexArrayObject -
 type TVertexArrayObject struct {
-	id uint32
+	dev *tDevice
+	id  uint32
 }
 
 // NewVao -
 func NewVao() *TVertexArrayObject {
-	ret := &TVertexArrayObject{}
-	gl.GenVertexArrays(1, &ret.id)
-	return ret
+	return newVao(dev)
+}
+
+func newVao(d *tDevice) *TVertexArrayObject {
+	return &TVertexArrayObject{dev: d, id: d.GenVertexArray()}
 }
 
 // Bind -
 func (o *TVertexArrayObject) Bind() {
 	// fmt.Println("vao: ", o)
-	gl.BindVertexArray(o.id)
+	o.dev.BindVertexArray(o.id)
 }
 
 // Unbind -
 func (o *TVertexArrayObject) Unbind() {
-	gl.BindVertexArray(0)
+	o.dev.BindVertexArray(0)
 }
 
 // Draw -
-func (o *TVertexArrayObject) Draw(mode uint32, elems *TElementArrayBuffer) {
+func (o *TVertexArrayObject) Draw(mode uint32, elems IElementBuffer) {
 	o.Bind()
 	elems.Bind()
 	elems.Draw(mode)
@@ -36,41 +41,161 @@ func (o *TVertexArrayObject) Draw(mode uint32, elems *TElementArrayBuffer) {
 	o.Unbind()
 }
 
-// AddAttribute -
-// func (o *TVertexArrayObject) AddAttribute(name string, buffer *TArrayBuffer, stride int32) {
-// 	index, err := globActiveProgram.AttribLocation(name)
-// 	_ = index
-// 	if err != nil {
-// 		panic(err)
-// 	}
-// 	buffer.Bind()
-// 	// _, typ, len := glDescribeType(
-// 	attribs := globActiveProgram.AttribParams()
-// 	desc := (*TAttribParams)(nil)
-// 	for i := range attribs {
-// 		if name == attribs[i].name {
-// 			desc = &attribs[i]
-// 			break
-// 		}
-// 	}
-// 	if desc == nil {
-// 		panic(fmt.Errorf("could not find attribute %q in program %v", name, globActiveProgram.id))
-// 	}
-// 	nm, typ, len := glDescribeType(desc.typ)
-// 	if len <= 0 {
-// 		panic(fmt.Errorf("bad type description %q %v %v of attribute %q", nm, typ, len, name))
-// 	}
-// 	len = len * desc.num
-// 	if len < 1 || 4 < len || typ != buffer.Type() {
-// 		nm, _, _ := glDescribeType(desc.typ)
-// 		panic(fmt.Errorf("want 1..4 * %q but got %v * %q", buffer.Type(), len, nm))
-// 	}
-// 	stride = len
-// 	gl.VertexAttribPointer(desc.index, stride, typ, false, 0, gl.PtrOffset(0))
-// 	gl.EnableVertexAttribArray(index)
+// glDescribeType returns the GLSL name, the scalar type and the number of
+// scalars of a type of an active attribute or uniform. The number is 0 for
+// unknown types.
+func glDescribeType(typ uint32) (string, uint32, int32) {
+	switch typ {
+	case gl.FLOAT:
+		return "float", gl.FLOAT, 1
+	case gl.FLOAT_VEC2:
+		return "vec2", gl.FLOAT, 2
+	case gl.FLOAT_VEC3:
+		return "vec3", gl.FLOAT, 3
+	case gl.FLOAT_VEC4:
+		return "vec4", gl.FLOAT, 4
+	case gl.FLOAT_MAT2:
+		return "mat2", gl.FLOAT, 4
+	case gl.FLOAT_MAT3:
+		return "mat3", gl.FLOAT, 9
+	case gl.FLOAT_MAT4:
+		return "mat4", gl.FLOAT, 16
+	case gl.FLOAT_MAT2x3:
+		return "mat2x3", gl.FLOAT, 6
+	case gl.FLOAT_MAT2x4:
+		return "mat2x4", gl.FLOAT, 8
+	case gl.FLOAT_MAT3x2:
+		return "mat3x2", gl.FLOAT, 6
+	case gl.FLOAT_MAT3x4:
+		return "mat3x4", gl.FLOAT, 12
+	case gl.FLOAT_MAT4x2:
+		return "mat4x2", gl.FLOAT, 8
+	case gl.FLOAT_MAT4x3:
+		return "mat4x3", gl.FLOAT, 12
+	case gl.INT:
+		return "int", gl.INT, 1
+	case gl.INT_VEC2:
+		return "ivec2", gl.INT, 2
+	case gl.INT_VEC3:
+		return "ivec3", gl.INT, 3
+	case gl.INT_VEC4:
+		return "ivec4", gl.INT, 4
+	case gl.UNSIGNED_INT:
+		return "uint", gl.UNSIGNED_INT, 1
+	case gl.UNSIGNED_INT_VEC2:
+		return "uvec2", gl.UNSIGNED_INT, 2
+	case gl.UNSIGNED_INT_VEC3:
+		return "uvec3", gl.UNSIGNED_INT, 3
+	case gl.UNSIGNED_INT_VEC4:
+		return "uvec4", gl.UNSIGNED_INT, 4
+	case gl.BOOL:
+		return "bool", gl.BOOL, 1
+	case gl.BOOL_VEC2:
+		return "bvec2", gl.BOOL, 2
+	case gl.BOOL_VEC3:
+		return "bvec3", gl.BOOL, 3
+	case gl.BOOL_VEC4:
+		return "bvec4", gl.BOOL, 4
+	case gl.SAMPLER_2D:
+		return "sampler2D", gl.INT, 1
+	case gl.SAMPLER_3D:
+		return "sampler3D", gl.INT, 1
+	case gl.SAMPLER_CUBE:
+		return "samplerCube", gl.INT, 1
+	case gl.SAMPLER_2D_SHADOW:
+		return "sampler2DShadow", gl.INT, 1
+	case gl.SAMPLER_2D_ARRAY:
+		return "sampler2DArray", gl.INT, 1
+	case gl.UNSIGNED_BYTE:
+		return "ubyte", gl.UNSIGNED_BYTE, 1
+	case gl.BYTE:
+		return "byte", gl.BYTE, 1
+	case gl.UNSIGNED_SHORT:
+		return "ushort", gl.UNSIGNED_SHORT, 1
+	case gl.SHORT:
+		return "short", gl.SHORT, 1
+	}
+	return fmt.Sprintf("0x%x", typ), 0, 0
+}
+
+// glMatrixColumns returns the number of columns of a matrix type, which
+// take a location each, 1 for other types.
+func glMatrixColumns(typ uint32) int32 {
+	switch typ {
+	case gl.FLOAT_MAT2, gl.FLOAT_MAT2x3, gl.FLOAT_MAT2x4:
+		return 2
+	case gl.FLOAT_MAT3, gl.FLOAT_MAT3x2, gl.FLOAT_MAT3x4:
+		return 3
+	case gl.FLOAT_MAT4, gl.FLOAT_MAT4x2, gl.FLOAT_MAT4x3:
+		return 4
+	}
+	return 1
+}
 
-// 	nm, _, _ = glDescribeType(typ)
-// 	fmt.Printf("new index: %v name: %q type: %v len: %v\n", desc.index, desc.name, nm, len)
-// 	nm, _, _ = glDescribeType(buffer.Type())
-// 	fmt.Printf("old index: %v name: %q type: %v len: %v\n", index, name, nm, stride)
-// }
+// IAttribBuffer - an array buffer whose elements feed one attribute, e.g.
+// TArrayBuffer[mgl32.Vec4].
+type IAttribBuffer interface {
+	IArrayBuffer
+	Type() uint32
+	Components() int
+}
+
+// AddAttribute - feeds the attribute name of the active program with the
+// elements of the buffer, which must have the scalar type and the number
+// of scalars the shader declares. Matrices and arrays take consecutive
+// locations. The vertex array and the buffer stay bound.
+func (o *TVertexArrayObject) AddAttribute(name string, buffer IAttribBuffer) error {
+	prog := o.dev.activeProgram
+	if prog == nil {
+		return fmt.Errorf("attribute %q: no active program", name)
+	}
+	desc := (*TAttribParams)(nil)
+	attribs := prog.AttribParams()
+	for i := range attribs {
+		if name == attribs[i].name {
+			desc = &attribs[i]
+			break
+		}
+	}
+	if desc == nil {
+		return fmt.Errorf("could not find attribute %q in program %v", name, prog.id)
+	}
+	nm, typ, n := glDescribeType(desc.typ)
+	if n <= 0 {
+		return fmt.Errorf("attribute %q has unsupported type %v", name, nm)
+	}
+	num := desc.num
+	if num < 1 {
+		num = 1
+	}
+	if buffer.Type() == 0 {
+		return fmt.Errorf("attribute %q: buffer mixes scalar types, use ApplyLayout", name)
+	}
+	if typ != buffer.Type() || int(n*num) != buffer.Components() {
+		bnm, _, _ := glDescribeType(buffer.Type())
+		tnm, _, _ := glDescribeType(typ)
+		want := nm
+		if num > 1 {
+			want = fmt.Sprintf("%v[%v]", nm, num)
+		}
+		return fmt.Errorf("attribute %q: want %v (%v x %v) but the buffer has %v x %v",
+			name, want, n*num, tnm, buffer.Components(), bnm)
+	}
+
+	cols := glMatrixColumns(desc.typ)
+	size := n / cols
+	scalar := int32(buffer.Stride()) / (n * num)
+	o.Bind()
+	buffer.Bind()
+	for i := int32(0); i < cols*num; i++ {
+		loc := desc.index + uint32(i)
+		offset := int(i * size * scalar)
+		o.dev.EnableVertexAttribArray(loc)
+		if typ == gl.FLOAT {
+			o.dev.VertexAttribPointer(loc, size, typ, false, int32(buffer.Stride()), offset)
+		} else {
+			o.dev.VertexAttribIPointer(loc, size, typ, int32(buffer.Stride()), offset)
+		}
+	}
+	return nil
+}
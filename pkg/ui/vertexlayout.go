@@ -0,0 +1,208 @@
+package ui
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+)
+
+// TVertexAttrib - where an attribute is in a vertex.
+type TVertexAttrib struct {
+	Name string
+	// Location is -1 if the attribute is looked up by Name in the program.
+	Location   int
+	Offset     int
+	Components int32
+	Type       uint32
+	Normalized bool
+}
+
+// TVertexLayout - the attributes of a vertex struct, see NewVertexLayout.
+type TVertexLayout struct {
+	Stride  int
+	Attribs []TVertexAttrib
+}
+
+// IArrayBuffer - an array buffer of any vertex type.
+type IArrayBuffer interface {
+	Bind()
+	Unbind()
+	Stride() int
+}
+
+// NewVertexLayout - describes the vertex struct T. Every exported field is
+// an attribute of 1..4 scalars of one type, named after the field unless
+// its tag says otherwise:
+//
+//	type tVertex struct {
+//		Pos   mgl32.Vec2 `gl:"Vertex,loc=0"`
+//		Color [4]uint8   `gl:",normalized"`
+//		pad   float32    // unexported fields are skipped
+//		Extra float32    `gl:"-"`
+//	}
+func NewVertexLayout[T any]() (*TVertexLayout, error) {
+	var zero T
+	t := reflect.TypeOf(&zero).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("vertex layout of %v: not a struct", t)
+	}
+	ret := &TVertexLayout{Stride: int(t.Size())}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		attr, skip, err := parseVertexTag(f)
+		if err == nil && !skip {
+			err = describeAttrib(&attr, f.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("vertex layout of %v: field %v: %v", t, f.Name, err)
+		}
+		if !skip {
+			ret.Attribs = append(ret.Attribs, attr)
+		}
+	}
+	if len(ret.Attribs) == 0 {
+		return nil, fmt.Errorf("vertex layout of %v: no attributes", t)
+	}
+	return ret, nil
+}
+
+// parseVertexTag parses `gl:"name,loc=N,normalized"`, name "-" skips the
+// field.
+func parseVertexTag(f reflect.StructField) (TVertexAttrib, bool, error) {
+	ret := TVertexAttrib{Name: f.Name, Location: -1, Offset: int(f.Offset)}
+	tag, ok := f.Tag.Lookup("gl")
+	if !ok {
+		return ret, false, nil
+	}
+	opts := strings.Split(tag, ",")
+	switch opts[0] {
+	case "-":
+		return ret, true, nil
+	case "":
+	default:
+		ret.Name = opts[0]
+	}
+	for _, opt := range opts[1:] {
+		opt = strings.TrimSpace(opt)
+		switch {
+		case opt == "normalized":
+			ret.Normalized = true
+		case strings.HasPrefix(opt, "loc="):
+			loc, err := strconv.Atoi(opt[len("loc="):])
+			if err != nil || loc < 0 {
+				return ret, false, fmt.Errorf("invalid location %q", opt)
+			}
+			ret.Location = loc
+		default:
+			return ret, false, fmt.Errorf("unknown option %q", opt)
+		}
+	}
+	return ret, false, nil
+}
+
+func describeAttrib(attr *TVertexAttrib, t reflect.Type) error {
+	typ, n, err := describeType(t)
+	if err != nil {
+		return err
+	}
+	if typ == 0 {
+		return fmt.Errorf("%v mixes scalar types", t)
+	}
+	if n < 1 || 4 < n {
+		return fmt.Errorf("%v has %v scalars, want 1..4", t, n)
+	}
+	attr.Type, attr.Components = typ, int32(n)
+	return nil
+}
+
+// ApplyLayout - binds the vertex array and the buffer and points the
+// attributes of the layout at the buffer. Attributes without a location are
+// looked up in prog, or in the active program if prog is nil. The scalars
+// and their number must match the declaration of the shader: float
+// attributes take float32 or normalized integers, integer ones integers of
+// the same signedness. Both stay bound, so an element buffer may be bound
+// to the vertex array next.
+func (o *TVertexArrayObject) ApplyLayout(buffer IArrayBuffer, layout *TVertexLayout, prog *TProgram) error {
+	if s := buffer.Stride(); s != 0 && s != layout.Stride {
+		return fmt.Errorf("vertex layout: stride %v differs from %v of the buffer", layout.Stride, s)
+	}
+	if prog == nil {
+		prog = o.dev.activeProgram
+	}
+	locs := make([]uint32, len(layout.Attribs))
+	for i, attr := range layout.Attribs {
+		if attr.Location >= 0 {
+			locs[i] = uint32(attr.Location)
+		} else {
+			if prog == nil {
+				return fmt.Errorf("vertex layout: no program to look attribute %q up in", attr.Name)
+			}
+			loc, err := prog.AttribLocation(attr.Name)
+			if err != nil {
+				return err
+			}
+			locs[i] = loc
+		}
+		if prog == nil {
+			continue
+		}
+		for _, desc := range prog.AttribParams() {
+			if desc.name == attr.Name || attr.Location >= 0 && desc.index == locs[i] {
+				if err := checkAttrib(attr, desc); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	o.Bind()
+	buffer.Bind()
+	for i, attr := range layout.Attribs {
+		o.dev.EnableVertexAttribArray(locs[i])
+		if attr.Type != gl.FLOAT && !attr.Normalized {
+			o.dev.VertexAttribIPointer(locs[i], attr.Components, attr.Type,
+				int32(layout.Stride), attr.Offset)
+			continue
+		}
+		o.dev.VertexAttribPointer(locs[i], attr.Components, attr.Type, attr.Normalized,
+			int32(layout.Stride), attr.Offset)
+	}
+	return nil
+}
+
+// checkAttrib checks that the attribute of a layout can feed the active
+// attribute desc.
+func checkAttrib(attr TVertexAttrib, desc TAttribParams) error {
+	nm, typ, n := glDescribeType(desc.typ)
+	if n <= 0 {
+		return fmt.Errorf("vertex layout: attribute %q has unsupported type %v", desc.name, nm)
+	}
+	if desc.num > 1 || glMatrixColumns(desc.typ) > 1 {
+		return fmt.Errorf("vertex layout: attribute %q is %v, use AddAttribute", desc.name, nm)
+	}
+	anm, _, _ := glDescribeType(attr.Type)
+	if attr.Normalized {
+		anm = "normalized " + anm
+	}
+	ok := false
+	switch typ {
+	case gl.FLOAT:
+		ok = attr.Type == gl.FLOAT || attr.Normalized
+	case gl.INT:
+		ok = !attr.Normalized && (attr.Type == gl.BYTE || attr.Type == gl.SHORT || attr.Type == gl.INT)
+	case gl.UNSIGNED_INT:
+		ok = !attr.Normalized && (attr.Type == gl.UNSIGNED_BYTE || attr.Type == gl.UNSIGNED_SHORT ||
+			attr.Type == gl.UNSIGNED_INT)
+	}
+	if !ok || attr.Components != n {
+		return fmt.Errorf("vertex layout: attribute %q: want %v but the layout has %v x %v",
+			desc.name, nm, attr.Components, anm)
+	}
+	return nil
+}
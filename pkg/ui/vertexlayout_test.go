@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	gl "github.com/go-gl/gl/v3.1/gles2"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type tLayoutVertex struct {
+	Pos   mgl32.Vec2
+	Color [4]uint8 `gl:",normalized"`
+	ID    uint32
+	Index [2]int16
+}
+
+func TestApplyLayout(t *testing.T) {
+	rec := useRecorder(t)
+	rec.DeclareAttrib("Pos", 1, gl.FLOAT_VEC2)
+	rec.DeclareAttrib("Color", 1, gl.FLOAT_VEC4)
+	rec.DeclareAttrib("ID", 1, gl.UNSIGNED_INT)
+	rec.DeclareAttrib("Index", 1, gl.INT_VEC2)
+	prog, err := NewProgram("vs", "fs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout, err := NewVertexLayout[tLayoutVertex]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Reset()
+	if err := NewVao().ApplyLayout(NewArrayBuffer[tLayoutVertex](), layout, prog); err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, c := range rec.Find("VertexAttribPointer", "VertexAttribIPointer") {
+		got = append(got, c.Name)
+	}
+	want := []string{"VertexAttribPointer", "VertexAttribPointer", "VertexAttribIPointer", "VertexAttribIPointer"}
+	if !equalStrings(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestApplyLayoutMismatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		attr  string
+		xtype uint32
+		num   int32
+		err   string
+	}{
+		{"float from integers", "Index", gl.FLOAT_VEC2, 1, "want vec2 but the layout has 2 x short"},
+		{"integer from floats", "Pos", gl.INT_VEC2, 1, "want ivec2 but the layout has 2 x float"},
+		{"integer from normalized", "Color", gl.UNSIGNED_INT_VEC4, 1, "normalized ubyte"},
+		{"signedness", "ID", gl.INT, 1, "want int but the layout has 1 x uint"},
+		{"components", "Pos", gl.FLOAT_VEC3, 1, "want vec3 but the layout has 2 x float"},
+		{"matrix", "Color", gl.FLOAT_MAT2, 1, "use AddAttribute"},
+		{"array", "ID", gl.UNSIGNED_INT, 2, "use AddAttribute"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := useRecorder(t)
+			rec.DeclareAttrib(tt.attr, tt.num, tt.xtype)
+			prog, err := NewProgram("vs", "fs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			layout, err := NewVertexLayout[tLayoutVertex]()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = NewVao().ApplyLayout(NewArrayBuffer[tLayoutVertex](), layout, prog)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}